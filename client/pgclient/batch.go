@@ -0,0 +1,134 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// LoadBatch is Load's result, and the row it records in TemplatorConfig.BatchTable when that's set. Its ID is the loaded
+// meta.Struct's UUID, which Load also writes to BatchIDTag fields. It is recorded before the load starts
+// and again when it ends, with the counts, or the error and zero counts when nothing was written.
+// It satisfies sql.Result, RowsAffected is Inserted plus Updated.
+type LoadBatch struct {
+	ID         string     `db:"batch_id" primarykey:"true"`
+	Table      string     `db:"table_name"` // schema qualified, unquoted
+	Strategy   string     `db:"strategy"`
+	StartedAt  time.Time  `db:"started_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+	Staged     int64      `db:"rows_staged"` // copied to the temp table
+	Inserted   int64      `db:"rows_inserted"`
+	Updated    int64      `db:"rows_updated"` // updated in place, or closed and replaced for meta.History
+	Skipped    int64      `db:"rows_skipped"` // staged but not written, unchanged or duplicate keys
+	Error      *string    `db:"error"`
+}
+
+// LastInsertId is not supported by postgres, use RETURNING instead
+func (b LoadBatch) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by postgres")
+}
+
+func (b LoadBatch) RowsAffected() (int64, error) {
+	return b.Inserted + b.Updated, nil
+}
+
+// newLoadBatch starts a LoadBatch for loading str
+func newLoadBatch(str meta.Struct, c *Client, strategy meta.UpdateStrategy) LoadBatch {
+	ConfigureStruct(&str, c.Config.Template)
+	return LoadBatch{
+		ID:        str.UUID,
+		Table:     strings.ToLower(str.TagIdentifier(c.Config.Template.TableNameTags)),
+		Strategy:  strategy.String(),
+		StartedAt: time.Now(),
+	}
+}
+
+// finish sets the batch's end time and Skipped count, or its error
+func (b *LoadBatch) finish(err error) {
+	finished := time.Now()
+	b.FinishedAt = &finished
+	if err != nil {
+		msg := err.Error()
+		b.Error = &msg
+		b.Inserted, b.Updated = 0, 0
+		return
+	}
+	b.Skipped = b.Staged - b.Inserted - b.Updated
+}
+
+// metadataClient returns a copy of c that writes to table in BatchSchema, for LoadBatch and Checkpoint rows.
+// It uses the package's TemplateConfig so their tags are read the same way whatever c's tags are.
+func (c *Client) metadataClient(table string) *Client {
+	mc := *c
+	cfg := TemplateConfig
+	cfg.Schema = c.Config.Template.BatchSchema
+	if cfg.Schema == "" {
+		cfg.Schema = c.Config.Template.Schema
+	}
+	cfg.Table = table
+	mc.Config.Template = cfg
+	return &mc
+}
+
+// undefinedTable returns true for errors from a missing table or schema
+func undefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "42P01" || pgErr.Code == "3F000")
+}
+
+// putMetadata upserts value, a pointer to a LoadBatch or Checkpoint, into table (see metadataClient),
+// creating it and its schema the first time. Each attempt runs in its own transaction, or savepoint,
+// so a failure doesn't abort a surrounding transaction.
+func (c *Client) putMetadata(ctx context.Context, table string, value any) error {
+	mc := c.metadataClient(table)
+	put := func(tx *Client) error {
+		_, err := tx.Put(ctx, value, PutOptions{Strategy: meta.ReplaceChanges})
+		return err
+	}
+
+	if err := mc.WithTx(ctx, put); !undefinedTable(err) {
+		return err
+	}
+	if err := mc.WithTx(ctx, func(tx *Client) error {
+		if err := tx.CreateSchema(ctx, value); err != nil {
+			return err
+		}
+		return tx.CreateTable(ctx, value)
+	}); err != nil {
+		return err
+	}
+	return mc.WithTx(ctx, put)
+}
+
+// loadCounts returns the rows inserted and updated by a PutTempToTable template, from the results of
+// each of its statements. The last one writes the rows: with countinserts it returns the inserted and
+// written counts, otherwise its command tag counts inserts. For meta.History the first one closes the
+// current versions being replaced, and each of those is an update.
+func loadCounts(results []*pgconn.Result, strategy meta.UpdateStrategy) (inserted, updated int64, err error) {
+	if len(results) == 0 {
+		return 0, 0, nil
+	}
+	last := results[len(results)-1]
+	if len(last.FieldDescriptions) == 2 && len(last.Rows) == 1 {
+		if inserted, err = strconv.ParseInt(string(last.Rows[0][0]), 10, 64); err != nil {
+			return 0, 0, err
+		}
+		written, err := strconv.ParseInt(string(last.Rows[0][1]), 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		return inserted, written - inserted, nil
+	}
+
+	written := last.CommandTag.RowsAffected()
+	if strategy == meta.History && len(results) > 1 {
+		updated = results[0].CommandTag.RowsAffected()
+		return written - updated, updated, nil
+	}
+	return written, 0, nil
+}
@@ -0,0 +1,98 @@
+package pgclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type batchIDTest struct {
+	ID      string `db:"id" primarykey:"true"`
+	Name    string `db:"name"`
+	BatchID string `db:"batch_id" batchid:"true"`
+}
+
+func TestLoadBatchTable(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	cfg.Schema = "meta"
+	cfg.Table = "load_batches"
+	text, err := pgclient.TemplateToText(pgclient.LoadBatch{}, pgclient.PGTemplates.CreateTable, &cfg, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`CREATE TABLE IF NOT EXISTS "meta"."load_batches"`,
+		`"table_name"	text NOT NULL,`,
+		`"started_at"	timestamp with time zone NOT NULL,`,
+		`"finished_at"	timestamp with time zone,`,
+		`"rows_skipped"	bigint NOT NULL,`,
+		`"error"	text,`,
+		`PRIMARY KEY ( "batch_id" )`,
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+
+	if pgclient.TemplateConfig.BatchTable != "" {
+		t.Errorf("batch tracking should be opt-in, got BatchTable %q", pgclient.TemplateConfig.BatchTable)
+	}
+
+	if affected, _ := (pgclient.LoadBatch{Inserted: 2, Updated: 3, Skipped: 4}).RowsAffected(); affected != 5 {
+		t.Errorf("RowsAffected: got %d, expected 5", affected)
+	}
+}
+
+func TestCountInsertsText(t *testing.T) {
+	for _, strategy := range []meta.UpdateStrategy{meta.ReplaceChanges, meta.ReplaceAll} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			tpl := pgclient.PGTemplates.PutTempToTableStrategy(strategy)
+			counted, err := pgclient.TemplateToText(structTest, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{"countinserts": true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expect := range []string{"with written as (\n", "returning ( xmax = 0 ) as inserted", "select count(*) filter ( where inserted ), count(*) from written"} {
+				if !strings.Contains(counted, expect) {
+					t.Errorf("expected %q in:\n%s", expect, counted)
+				}
+			}
+
+			plain, err := pgclient.TemplateToText(structTest, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(plain, "written") {
+				t.Errorf("unexpected counts without countinserts:\n%s", plain)
+			}
+		})
+	}
+}
+
+func TestBatchIDColumns(t *testing.T) {
+	str, err := meta.ToStruct(batchIDTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := pgclient.TemplateConfig
+	if got := pgclient.ColumnNames(pgclient.UpdateFields(str.Fields(), cfg), cfg); !reflect.DeepEqual(got, []string{"name"}) {
+		t.Errorf("UpdateFields: got %v", got)
+	}
+	set, err := pgclient.UpdateSet(str.Fields(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{`"name" = excluded."name"`, `"batch_id" = excluded."batch_id"`}; !reflect.DeepEqual(set, expect) {
+		t.Errorf("UpdateSet: got %v, expected %v", set, expect)
+	}
+
+	text, err := pgclient.TemplateToText(batchIDTest{}, pgclient.PGTemplates.PutTempToTableReplaceChanges, &cfg, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `where ( dst."name" ) is distinct from ( excluded."name" )`; !strings.Contains(text, expect) {
+		t.Errorf("expected batch_id to be left out of the comparison, %q in:\n%s", expect, text)
+	}
+}
@@ -0,0 +1,25 @@
+package pgclient_test
+
+import (
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+func BenchmarkTemplateToText(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := pgclient.DefaultCreateTableText(structTest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNamedArgs(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := pgclient.NamedArgs(structTest, pgclient.TemplateConfig); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
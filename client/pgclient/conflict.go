@@ -0,0 +1,141 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrConflict is matched by every ConflictError, use errors.Is(err, ErrConflict)
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError is returned by Put and Load when an update's VersionTag value doesn't match the
+// table's, meaning the row changed since it was read. The row is left as it is in the table.
+type ConflictError struct {
+	Table   string         // the quoted table, see TableIdentifier
+	Key     map[string]any // the row's primary key values, by column name
+	Version int64          // the version being written
+	Current int64          // the version in the table
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s on %s %v: expected version %d, found %d", ErrConflict, e.Table, e.Key, e.Version, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// versioned returns the column name of str's first VersionTag field and its primary key column names.
+// ok is false unless str has both, versions can't be checked without a key.
+func versioned(str meta.Struct, cfg client.TemplatorConfig) (version string, primarykey []string, ok bool) {
+	if cfg.VersionTag == "" {
+		return "", nil, false
+	}
+	fields := WriteFields(str, cfg)
+	versions := ColumnNames(fields.WithTagTrue(cfg.VersionTag), cfg)
+	primarykey = ColumnNames(fields.WithTagTrue(cfg.PrimaryKeyTag), cfg)
+	if len(versions) == 0 || len(primarykey) == 0 {
+		return "", nil, false
+	}
+	return versions[0], primarykey, true
+}
+
+// versionConflicts checks rows, which an upsert left unchanged, against the table with the GetVersion
+// template and returns a ConflictError for each whose version doesn't match, joined with errors.Join.
+// Rows that aren't in the table, or that match, were skipped for having no changes.
+func (c *Client) versionConflicts(ctx context.Context, value any, str meta.Struct, rows []any) error {
+	cfg := c.Config.Template
+	version, primarykey, ok := versioned(str, cfg)
+	if !ok || len(rows) == 0 {
+		return nil
+	}
+	sqlText, err := c.TemplateToText(value, c.Templator.GetVersion)
+	if err != nil {
+		return err
+	}
+	query := ParseNamed(sqlText)
+	table, err := TableIdentifier(&str, cfg)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []error
+	for _, row := range rows {
+		args, err := NamedArgs(row, cfg)
+		if err != nil {
+			return err
+		}
+		expected, ok := versionNumber(args[version])
+		if !ok {
+			return fmt.Errorf("%s: version %v is not an integer", table, args[version])
+		}
+		params, err := query.Bind(args)
+		if err != nil {
+			return err
+		}
+
+		var current int64
+		switch err := c.DB().QueryRow(ctx, query.SQL, params...).Scan(&current); {
+		case errors.Is(err, pgx.ErrNoRows):
+			continue
+		case err != nil:
+			return err
+		}
+		if current != expected {
+			key := map[string]any{}
+			for _, column := range primarykey {
+				key[column] = args[column]
+			}
+			conflicts = append(conflicts, &ConflictError{Table: table, Key: key, Version: expected, Current: current})
+		}
+	}
+	return errors.Join(conflicts...)
+}
+
+// tempVersionConflicts runs the VersionConflicts template against the loaded temp table and returns a
+// ConflictError for each row, joined with errors.Join
+func (c *Client) tempVersionConflicts(ctx context.Context, value any, str meta.Struct) error {
+	cfg := c.Config.Template
+	_, primarykey, ok := versioned(str, cfg)
+	if !ok {
+		return nil
+	}
+	sqlText, err := c.TemplateToText(value, c.Templator.VersionConflicts)
+	if err != nil {
+		return err
+	}
+	table, err := TableIdentifier(&str, cfg)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB().Query(ctx, sqlText)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var conflicts []error
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		key := map[string]any{}
+		for i, column := range primarykey {
+			key[column] = values[i]
+		}
+		expected, _ := versionNumber(values[len(primarykey)])
+		current, _ := versionNumber(values[len(primarykey)+1])
+		conflicts = append(conflicts, &ConflictError{Table: table, Key: key, Version: expected, Current: current})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return errors.Join(conflicts...)
+}
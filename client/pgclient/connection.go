@@ -0,0 +1,260 @@
+package pgclient
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// ConnConfig expands cc and parses the result into a pgx.ConnConfig. PoolOptions are ignored.
+func ConnConfig(cc client.ConnectionConfig) (*pgx.ConnConfig, error) {
+	expanded, err := ExpandConnectionConfig(cc)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.ParseConfig(ConnectionString(withoutPoolOptions(expanded)))
+}
+
+// ExpandConnectionConfig applies the ExpandEnvVars and ExpandFileContents flags to every string
+// component of cc, including Options values. File contents are expanded after env vars, so
+// {file:${HOME}/.pgpass_mine} works.
+func ExpandConnectionConfig(cc client.ConnectionConfig) (expanded client.ConnectionConfig, err error) {
+	if !cc.ExpandEnvVars && !cc.ExpandFileContents {
+		return cc, nil
+	}
+	// meta.ExpandFileContents panics when it can't read a file
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("expanding connection config: %v", r)
+		}
+	}()
+
+	expand := func(s string) string {
+		if cc.ExpandEnvVars {
+			s = meta.ExpandEnvVars(s)
+		}
+		if cc.ExpandFileContents {
+			s = meta.ExpandFileContents(s)
+		}
+		return s
+	}
+
+	expanded = cc
+	for _, s := range []*string{
+		&expanded.Host,
+		&expanded.Protocol,
+		&expanded.ConnectionString,
+		&expanded.DataSourceName,
+		&expanded.Database,
+		&expanded.Schema,
+		&expanded.Username,
+		&expanded.Password,
+	} {
+		*s = expand(*s)
+	}
+	if cc.Options != nil {
+		expanded.Options = map[string]string{}
+		for k, v := range cc.Options {
+			expanded.Options[k] = expand(v)
+		}
+	}
+	return expanded, nil
+}
+
+// ConnectionString returns cc.ConnectionString if it is set, otherwise it builds a postgres:// URL
+// from the components. A Password without a Username is passed as the password parameter. Options are passed as parameters, eg sslmode or application_name, and are
+// added to cc.ConnectionString too, replacing any of the same name. Protocol is ignored, pgx
+// connects over tcp, or a unix socket when Host is a directory.
+func ConnectionString(cc client.ConnectionConfig) string {
+	if cc.ConnectionString != "" {
+		return withOptions(cc.ConnectionString, cc.Options)
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		Path:   "/" + cc.Database,
+	}
+	switch {
+	case cc.Username != "" && cc.Password != "":
+		u.User = url.UserPassword(cc.Username, cc.Password)
+	case cc.Username != "":
+		u.User = url.User(cc.Username)
+	}
+
+	query := url.Values{}
+	if cc.Username == "" && cc.Password != "" {
+		// the userinfo can't hold a password without a user
+		query.Set("password", cc.Password)
+	}
+	for k, v := range cc.Options {
+		query.Set(k, v)
+	}
+	switch {
+	case strings.HasPrefix(cc.Host, "/"):
+		// unix socket directories can't be part of the authority
+		query.Set("host", cc.Host)
+		if cc.Port != 0 {
+			query.Set("port", strconv.Itoa(cc.Port))
+		}
+	case cc.Port != 0:
+		u.Host = net.JoinHostPort(cc.Host, strconv.Itoa(cc.Port))
+	case strings.Contains(cc.Host, ":"):
+		// ipv6 literals are bracketed, or their last group reads as the port
+		u.Host = "[" + cc.Host + "]"
+	default:
+		u.Host = cc.Host
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// withOptions adds options to a URL or keyword/value dsn, replacing parameters of the same name
+func withOptions(dsn string, options map[string]string) string {
+	if len(options) == 0 {
+		return dsn
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// leave it for pgx to report
+			return dsn
+		}
+		query := u.Query()
+		for k, v := range options {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(dsn))
+	for _, k := range keys {
+		// later keywords replace earlier ones
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(options[k])
+		fmt.Fprintf(&b, " %s='%s'", k, escaped)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ParseConnectionString parses a URL (postgres://...) or keyword/value (host=... dbname=...) DSN
+// into ConnectionConfig components. Parameters without a matching field are returned in Options.
+func ParseConnectionString(dsn string) (client.ConnectionConfig, error) {
+	cc := client.ConnectionConfig{Type: "postgres"}
+	var params map[string]string
+
+	switch {
+	case strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://"):
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return cc, err
+		}
+		cc.Protocol = u.Scheme
+		if u.User != nil {
+			cc.Username = u.User.Username()
+			cc.Password, _ = u.User.Password()
+		}
+		params = map[string]string{
+			"host":   u.Hostname(),
+			"port":   u.Port(),
+			"dbname": strings.TrimPrefix(u.Path, "/"),
+		}
+		for k, v := range u.Query() {
+			params[k] = v[0]
+		}
+	default:
+		var err error
+		params, err = parseKeywordValue(dsn)
+		if err != nil {
+			return cc, err
+		}
+	}
+
+	for k, v := range params {
+		switch k {
+		case "host":
+			cc.Host = v
+		case "port":
+			if v == "" {
+				continue
+			}
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return cc, fmt.Errorf("invalid port %q: %w", v, err)
+			}
+			cc.Port = port
+		case "dbname", "database":
+			cc.Database = v
+		case "user":
+			cc.Username = v
+		case "password":
+			cc.Password = v
+		default:
+			if cc.Options == nil {
+				cc.Options = map[string]string{}
+			}
+			cc.Options[k] = v
+		}
+	}
+	return cc, nil
+}
+
+// parseKeywordValue parses libpq style keyword/value pairs
+func parseKeywordValue(dsn string) (map[string]string, error) {
+	params := map[string]string{}
+	s := strings.TrimSpace(dsn)
+	for len(s) > 0 {
+		eq := strings.IndexRune(s, '=')
+		if eq == -1 {
+			return nil, fmt.Errorf("invalid dsn, missing '=' after %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		value, rest, err := readKeywordValue(strings.TrimLeft(s[eq+1:], " \t\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid dsn value for %s: %w", key, err)
+		}
+		params[key] = value
+		s = strings.TrimLeft(rest, " \t\n")
+	}
+	return params, nil
+}
+
+// readKeywordValue reads a single value and returns it with the remainder of s. Values may be
+// single quoted, and backslash escapes quotes and backslashes.
+func readKeywordValue(s string) (string, string, error) {
+	var value strings.Builder
+	quoted := strings.HasPrefix(s, "'")
+	if quoted {
+		s = s[1:]
+	}
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\' && i+1 < len(s):
+			i++
+			value.WriteByte(s[i])
+		case quoted && ch == '\'':
+			return value.String(), s[i+1:], nil
+		case !quoted && (ch == ' ' || ch == '\t' || ch == '\n'):
+			return value.String(), s[i+1:], nil
+		default:
+			value.WriteByte(ch)
+		}
+	}
+	if quoted {
+		return "", "", fmt.Errorf("unterminated quote")
+	}
+	return value.String(), "", nil
+}
@@ -0,0 +1,80 @@
+package pgclient_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type constraintTest struct {
+	ID        int64          `db:"id" primarykey:"true"`
+	Name      string         `db:"name" check:"name <> ''"`
+	Nickname  *string        `db:"nickname"`
+	Note      sql.NullString `db:"note"`
+	Optional  string         `db:"optional" notnull:"-"`
+	Required  *string        `db:"required" notnull:"true"`
+	CreatedAt time.Time      `db:"created_at" default:"now()"`
+	NameLen   int            `db:"name_len" generated:"length(name)"`
+}
+
+func TestColumnConstraints(t *testing.T) {
+	text, err := pgclient.DefaultCreateTableText(constraintTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`"id"` + "\tbigint,",
+		`"name"` + "\ttext NOT NULL CHECK ( name <> '' ),",
+		`"nickname"` + "\ttext,",
+		`"note"` + "\ttext,",
+		`"optional"` + "\ttext,",
+		`"required"` + "\ttext NOT NULL,",
+		`"created_at"` + "\ttimestamp with time zone NOT NULL DEFAULT now(),",
+		`"name_len"` + "\tbigint GENERATED ALWAYS AS ( length(name) ) STORED,",
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+
+	cfg := pgclient.TemplateConfig
+	cfg.NullableByDefault = true
+	text, err = pgclient.TemplateToText(constraintTest{}, pgclient.PGTemplates.CreateTable, &cfg, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(text, "NOT NULL") != 1 {
+		t.Errorf("expected only the tagged NOT NULL with NullableByDefault:\n%s", text)
+	}
+
+	for name, tpl := range map[string]string{
+		"Put":                          pgclient.PGTemplates.Put,
+		"PutTempToTable":               pgclient.PGTemplates.PutTempToTable,
+		"PutReplaceChanges":            pgclient.PGTemplates.PutReplaceChanges,
+		"PutTempToTableReplaceChanges": pgclient.PGTemplates.PutTempToTableReplaceChanges,
+	} {
+		text, err := pgclient.TemplateToText(constraintTest{}, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(text, "name_len") {
+			t.Errorf("%s writes generated column:\n%s", name, text)
+		}
+	}
+}
+
+func TestTemplatorConfigMergeNullableByDefault(t *testing.T) {
+	cfg := client.NewTemplatorConfig(pgclient.TemplateConfig, client.TemplatorConfig{NullableByDefault: true})
+	if !cfg.NullableByDefault || cfg.GeneratedTag != "generated" {
+		t.Errorf("unexpected merged config: %+v", cfg)
+	}
+
+	cfg.Merge(client.TemplatorConfig{Schema: "other"})
+	if !cfg.NullableByDefault || cfg.Schema != "other" {
+		t.Errorf("merging a config without NullableByDefault should keep it: %+v", cfg)
+	}
+}
@@ -0,0 +1,49 @@
+package pgclient
+
+import (
+	"context"
+	"strings"
+)
+
+// Exec renders tpl for value and executes it. It is intended for templates without named parameters.
+func (c *Client) Exec(ctx context.Context, tpl string, value any) (Result, error) {
+	sqlText, err := c.TemplateToText(value, tpl)
+	if err != nil {
+		return Result{}, err
+	}
+	tag, err := c.DB().Exec(ctx, sqlText)
+	return Result{tag}, err
+}
+
+func (c *Client) CreateSchema(ctx context.Context, value any) error {
+	_, err := c.Exec(ctx, c.Templator.CreateSchema, value)
+	return err
+}
+
+func (c *Client) DropSchema(ctx context.Context, value any) error {
+	_, err := c.Exec(ctx, c.Templator.DropSchema, value)
+	return err
+}
+
+// CreateTable creates value's table, then its indexes (see CreateIndexes)
+func (c *Client) CreateTable(ctx context.Context, value any) error {
+	if _, err := c.Exec(ctx, c.Templator.CreateTable, value); err != nil {
+		return err
+	}
+	return c.CreateIndexes(ctx, value)
+}
+
+// CreateIndexes creates any indexes declared in value's field tags, see Indexes
+func (c *Client) CreateIndexes(ctx context.Context, value any) error {
+	sqlText, err := c.TemplateToText(value, c.Templator.CreateIndexes)
+	if err != nil || strings.TrimSpace(sqlText) == "" {
+		return err
+	}
+	_, err = c.DB().Exec(ctx, sqlText)
+	return err
+}
+
+func (c *Client) DropTable(ctx context.Context, value any) error {
+	_, err := c.Exec(ctx, c.Templator.DropTable, value)
+	return err
+}
@@ -0,0 +1,264 @@
+package pgclient
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// FilterOp is the comparison a Filter makes
+type FilterOp int8
+
+func (op FilterOp) String() string {
+	switch op {
+	case FilterEq:
+		return "Eq"
+	case FilterIn:
+		return "In"
+	case FilterRange:
+		return "Range"
+	case FilterLike:
+		return "Like"
+	case FilterIsNull:
+		return "IsNull"
+	default:
+		return ""
+	}
+}
+
+const (
+	FilterEq     FilterOp = iota + 1 // column = value
+	FilterIn                         // column in ( values... ), no values matches nothing
+	FilterRange                      // from <= column < to, a nil bound is open
+	FilterLike                       // column like pattern
+	FilterIsNull                     // column is null
+)
+
+// Filter is a condition on a column, see GetOptions.Where. Use the constructors, eg Eq, In, or Not(IsNull()).
+type Filter struct {
+	Op     FilterOp
+	Values []any
+	Not    bool // negates the condition
+}
+
+// Eq matches column = value, or column is null for a nil value or nil pointer
+func Eq(value any) Filter {
+	if rv := reflect.ValueOf(value); value == nil || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return IsNull()
+	}
+	return Filter{Op: FilterEq, Values: []any{value}}
+}
+
+// In matches any of values
+func In(values ...any) Filter {
+	return Filter{Op: FilterIn, Values: values}
+}
+
+// Range matches from <= column < to. Either bound may be nil to leave it open.
+func Range(from, to any) Filter {
+	return Filter{Op: FilterRange, Values: []any{from, to}}
+}
+
+// Like matches a like pattern, eg "abc%"
+func Like(pattern string) Filter {
+	return Filter{Op: FilterLike, Values: []any{pattern}}
+}
+
+// IsNull matches NULL
+func IsNull() Filter {
+	return Filter{Op: FilterIsNull}
+}
+
+// Not negates f
+func Not(f Filter) Filter {
+	f.Not = !f.Not
+	return f
+}
+
+// condition renders f for a quoted column, adding its values to args as named parameters prefixed with param
+func (f Filter) condition(column, param string, args map[string]any) (string, error) {
+	bind := func(i int) string {
+		name := fmt.Sprintf("%s_%d", param, i)
+		args[name] = f.Values[i]
+		return ":" + name
+	}
+
+	var condition string
+	switch f.Op {
+	case FilterEq, FilterLike:
+		if len(f.Values) != 1 {
+			return "", fmt.Errorf("%s filter on %s needs one value, got %d", f.Op, column, len(f.Values))
+		}
+		operator := "="
+		if f.Op == FilterLike {
+			operator = "like"
+		}
+		condition = fmt.Sprintf("%s %s %s", column, operator, bind(0))
+	case FilterIn:
+		if len(f.Values) == 0 {
+			condition = "false"
+			break
+		}
+		var params []string
+		for i := range f.Values {
+			params = append(params, bind(i))
+		}
+		condition = fmt.Sprintf("%s in ( %s )", column, strings.Join(params, ", "))
+	case FilterRange:
+		if len(f.Values) != 2 {
+			return "", fmt.Errorf("%s filter on %s needs two values, got %d", f.Op, column, len(f.Values))
+		}
+		var bounds []string
+		if f.Values[0] != nil {
+			bounds = append(bounds, fmt.Sprintf("%s >= %s", column, bind(0)))
+		}
+		if f.Values[1] != nil {
+			bounds = append(bounds, fmt.Sprintf("%s < %s", column, bind(1)))
+		}
+		if len(bounds) == 0 {
+			bounds = []string{"true"}
+		}
+		condition = strings.Join(bounds, " and ")
+	case FilterIsNull:
+		condition = column + " is null"
+	default:
+		return "", fmt.Errorf("unknown filter operator %d on %s", f.Op, column)
+	}
+
+	if f.Not {
+		return "not ( " + condition + " )", nil
+	}
+	return condition, nil
+}
+
+// columnLookup maps lowercased field names and column names to value's column names
+func columnLookup(value any, cfg client.TemplatorConfig) (map[string]string, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := ColumnFields(str, cfg)
+	columns := ColumnNames(fields, cfg)
+
+	lookup := map[string]string{}
+	for i, field := range fields {
+		lookup[strings.ToLower(field.Name)] = columns[i]
+	}
+	for _, column := range columns {
+		lookup[column] = column
+	}
+	return lookup, nil
+}
+
+// WhereClause renders filters on value's columns as a parameterized condition, joined with and, and
+// returns the named parameters it uses. Keys are column or field names, case insensitive. Values are
+// Filters, or plain values compared with Eq. Conditions are in column order so the SQL is stable.
+func WhereClause(value any, filters meta.ValueMap, cfg client.TemplatorConfig) (string, map[string]any, error) {
+	args := map[string]any{}
+	if len(filters) == 0 {
+		return "", args, nil
+	}
+	lookup, err := columnLookup(value, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	byColumn := map[string][]Filter{}
+	for key, v := range filters {
+		column, ok := lookup[strings.ToLower(key)]
+		if !ok {
+			return "", nil, fmt.Errorf("cannot filter on %s, it is not a column of %T", key, value)
+		}
+		filter, ok := v.(Filter)
+		if !ok {
+			filter = Eq(v)
+		}
+		byColumn[column] = append(byColumn[column], filter)
+	}
+
+	columns := make([]string, 0, len(byColumn))
+	for column := range byColumn {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	var conditions []string
+	for _, column := range columns {
+		quoted, err := QuoteIdentifier(column)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, filter := range byColumn[column] {
+			condition, err := filter.condition(quoted, fmt.Sprintf("where_%d", len(conditions)), args)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+	return strings.Join(conditions, " and "), args, nil
+}
+
+// ExampleFilters returns an Eq filter for each of example's non-zero column fields, keyed by column name.
+// Zero values can't be told apart from unset ones, filter on them with GetOptions.Where instead.
+func ExampleFilters(example any, cfg client.TemplatorConfig) (meta.ValueMap, error) {
+	str, err := meta.ToStruct(example)
+	if err != nil {
+		return nil, err
+	}
+	args, err := NamedArgs(example, cfg)
+	if err != nil {
+		return nil, err
+	}
+	filters := meta.ValueMap{}
+	rv := reflect.Indirect(reflect.ValueOf(example))
+	if rv.Kind() != reflect.Struct {
+		return filters, nil
+	}
+	fields := ColumnFields(str, cfg)
+	for i, column := range ColumnNames(fields, cfg) {
+		fv, err := rv.FieldByIndexErr(fields[i].StructField.Index)
+		if err != nil || fv.IsZero() {
+			continue
+		}
+		filters[column] = Eq(args[column])
+	}
+	return filters, nil
+}
+
+// OrderClause renders columns, each an optional " asc" or " desc" after a column or field name,
+// as a quoted order by list for value
+func OrderClause(value any, columns []string, cfg client.TemplatorConfig) (string, error) {
+	if len(columns) == 0 {
+		return "", nil
+	}
+	lookup, err := columnLookup(value, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var order []string
+	for _, c := range columns {
+		name, direction := strings.TrimSpace(c), ""
+		if i := strings.LastIndex(name, " "); i != -1 {
+			switch d := strings.ToLower(name[i+1:]); d {
+			case "asc", "desc":
+				name, direction = strings.TrimSpace(name[:i]), " "+d
+			}
+		}
+		column, ok := lookup[strings.ToLower(name)]
+		if !ok {
+			return "", fmt.Errorf("cannot order by %s, it is not a column of %T", c, value)
+		}
+		quoted, err := QuoteIdentifier(column)
+		if err != nil {
+			return "", err
+		}
+		order = append(order, quoted+direction)
+	}
+	return strings.Join(order, ", "), nil
+}
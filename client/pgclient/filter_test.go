@@ -0,0 +1,149 @@
+package pgclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+func TestWhereClause(t *testing.T) {
+	var testCases = []struct {
+		Name    string
+		Filters meta.ValueMap
+		Expect  string
+		Args    map[string]any
+		Err     bool
+	}{
+		{Name: "none", Filters: nil, Expect: "", Args: map[string]any{}},
+		{
+			Name:    "plain value",
+			Filters: meta.ValueMap{"string_field": "a"},
+			Expect:  `"string_field" = :where_0_0`,
+			Args:    map[string]any{"where_0_0": "a"},
+		},
+		{
+			Name:    "field name",
+			Filters: meta.ValueMap{"IntField": pgclient.Eq(3)},
+			Expect:  `"int_field" = :where_0_0`,
+			Args:    map[string]any{"where_0_0": 3},
+		},
+		{
+			Name:    "in",
+			Filters: meta.ValueMap{"int_field": pgclient.In(1, 2)},
+			Expect:  `"int_field" in ( :where_0_0, :where_0_1 )`,
+			Args:    map[string]any{"where_0_0": 1, "where_0_1": 2},
+		},
+		{Name: "empty in", Filters: meta.ValueMap{"int_field": pgclient.In()}, Expect: "false", Args: map[string]any{}},
+		{
+			Name:    "range",
+			Filters: meta.ValueMap{"float_field": pgclient.Range(1.5, 2.5)},
+			Expect:  `"float_field" >= :where_0_0 and "float_field" < :where_0_1`,
+			Args:    map[string]any{"where_0_0": 1.5, "where_0_1": 2.5},
+		},
+		{
+			Name:    "open range",
+			Filters: meta.ValueMap{"float_field": pgclient.Range(nil, 2.5)},
+			Expect:  `"float_field" < :where_0_1`,
+			Args:    map[string]any{"where_0_1": 2.5},
+		},
+		{
+			Name:    "like",
+			Filters: meta.ValueMap{"string_field": pgclient.Like("A%")},
+			Expect:  `"string_field" like :where_0_0`,
+			Args:    map[string]any{"where_0_0": "A%"},
+		},
+		{Name: "is null", Filters: meta.ValueMap{"string_pointer_field": nil}, Expect: `"string_pointer_field" is null`, Args: map[string]any{}},
+		{
+			Name:    "not null",
+			Filters: meta.ValueMap{"string_pointer_field": pgclient.Not(pgclient.IsNull())},
+			Expect:  `not ( "string_pointer_field" is null )`,
+			Args:    map[string]any{},
+		},
+		{
+			Name:    "several in column order",
+			Filters: meta.ValueMap{"string_field": "a", "bool_field": true},
+			Expect:  `"bool_field" = :where_0_0 and "string_field" = :where_1_0`,
+			Args:    map[string]any{"where_0_0": true, "where_1_0": "a"},
+		},
+		{Name: "unknown column", Filters: meta.ValueMap{"missing": 1}, Err: true},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			where, args, err := pgclient.WhereClause(structTest, v.Filters, pgclient.TemplateConfig)
+			switch {
+			case v.Err && err == nil:
+				t.Errorf("expected an error, got %s", where)
+			case v.Err:
+			case err != nil:
+				t.Fatal(err)
+			case where != v.Expect || !reflect.DeepEqual(args, v.Args):
+				t.Errorf("got %s %v, expected %s %v", where, args, v.Expect, v.Args)
+			}
+		})
+	}
+}
+
+func TestExampleFilters(t *testing.T) {
+	filters, err := pgclient.ExampleFilters(StructTest{StringField: "a", IntField: 2}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := meta.ValueMap{"string_field": pgclient.Eq("a"), "int_field": pgclient.Eq(2)}
+	if !reflect.DeepEqual(filters, expect) {
+		t.Errorf("got %v, expected %v", filters, expect)
+	}
+}
+
+func TestOrderClause(t *testing.T) {
+	order, err := pgclient.OrderClause(structTest, []string{"time_field desc", "IDHash", "int_field ASC"}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `"time_field" desc, "_id_hash", "int_field" asc`; order != expect {
+		t.Errorf("got %s, expected %s", order, expect)
+	}
+	if _, err := pgclient.OrderClause(structTest, []string{"time_field; drop table users"}, pgclient.TemplateConfig); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
+
+func TestGetFilterText(t *testing.T) {
+	where, args, err := pgclient.WhereClause(structTest, meta.ValueMap{"int_field": pgclient.In(1, 2)}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get, err := pgclient.TemplateToText(structTest, pgclient.PGTemplates.Get, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+		"where":     where,
+		"orderby":   `"int_field" desc`,
+		"rowlimit":  10,
+		"rowoffset": 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"where " + where, `order by "int_field" desc`, "limit 10", "offset 20"} {
+		if !strings.Contains(get, expect) {
+			t.Errorf("expected %q in:\n%s", expect, get)
+		}
+	}
+	if _, params, err := pgclient.BindNamed(get, args); err != nil || !reflect.DeepEqual(params, []any{1, 2}) {
+		t.Errorf("bind: got %v, %v", params, err)
+	}
+
+	page, err := pgclient.TemplateToText(structTest, pgclient.PGTemplates.GetPage, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+		"after":    true,
+		"where":    where,
+		"pagesize": 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `where ( "_id_hash" ) > ( :_id_hash ) and ( ` + where + " )"; !strings.Contains(page, expect) {
+		t.Errorf("expected %q in:\n%s", expect, page)
+	}
+}
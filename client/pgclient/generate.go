@@ -0,0 +1,161 @@
+package pgclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/exiledavatar/gotoolkit/client"
+)
+
+// NullImportPath is the import path generated structs use for null.Nullable
+const NullImportPath = "github.com/exiledavatar/gotools/null"
+
+// GenerateOptions controls the source GenerateStruct writes
+type GenerateOptions struct {
+	Package    string // package clause and imports are only written if set
+	Name       string // struct name, defaults to the table name in CamelCase
+	NullImport string // defaults to NullImportPath
+}
+
+// GenerateStruct reads schema.table's columns and primary key and returns a formatted Go struct for it
+func (c *Client) GenerateStruct(ctx context.Context, schema, table string, opts GenerateOptions) ([]byte, error) {
+	columns, err := c.TableColumns(ctx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns found for %s.%s", schema, table)
+	}
+	return GenerateStruct(table, columns, c.Config.Template, opts)
+}
+
+// GenerateStruct returns a formatted Go struct for columns. Each field is tagged with its column
+// name ("db"), its column type (cfg.DataTypeTag), and cfg.PrimaryKeyTag if needed, so the struct
+// round trips through PGTemplates.CreateTable. Go types come from TypeMap.From, falling back to
+// string, and nullable columns use null.Nullable. A TableName method (see Tabler) keeps the
+// struct on table, eg user_accounts rather than the lowercased struct name useraccounts.
+func GenerateStruct(table string, columns []Column, cfg client.TemplatorConfig, opts GenerateOptions) ([]byte, error) {
+	if opts.Name == "" {
+		opts.Name = GoName(table)
+	}
+	if opts.NullImport == "" {
+		opts.NullImport = NullImportPath
+	}
+
+	imports := map[string]bool{}
+	names := map[string]int{}
+	var fields bytes.Buffer
+	for _, column := range columns {
+		name := GoName(column.Name)
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, names[name])
+		}
+
+		goType, pkgPath := GoType(column.Type)
+		if pkgPath != "" {
+			imports[pkgPath] = true
+		}
+		if column.Nullable && !column.PrimaryKey {
+			goType = fmt.Sprintf("null.Nullable[%s]", goType)
+			imports[opts.NullImport] = true
+		}
+
+		tags := fmt.Sprintf(`db:"%s" %s:"%s"`, column.Name, cfg.DataTypeTag, column.Type)
+		if column.PrimaryKey {
+			tags += fmt.Sprintf(` %s:"true"`, cfg.PrimaryKeyTag)
+		}
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", name, goType, tags)
+	}
+
+	var src bytes.Buffer
+	if opts.Package != "" {
+		fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+		if len(imports) > 0 {
+			paths := make([]string, 0, len(imports))
+			for path := range imports {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			src.WriteString("import (\n")
+			for _, path := range paths {
+				fmt.Fprintf(&src, "\t%q\n", path)
+			}
+			src.WriteString(")\n\n")
+		}
+	}
+	fmt.Fprintf(&src, "type %s struct {\n%s}\n\n", opts.Name, fields.String())
+	fmt.Fprintf(&src, "// TableName returns the table %s was generated from, see pgclient.Tabler\n", opts.Name)
+	fmt.Fprintf(&src, "func (%s) TableName() string {\n\treturn %q\n}\n", opts.Name, table)
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated struct: %w\n%s", err, src.String())
+	}
+	return out, nil
+}
+
+// GoType returns the Go type TypeMap.From maps pgType to, and the package path it needs,
+// if any. Arrays become slices and unknown types become string.
+func GoType(pgType string) (string, string) {
+	normalized := NormalizeType(pgType)
+	var slices string
+	for strings.HasSuffix(normalized, "[]") {
+		slices += "[]"
+		normalized = strings.TrimSuffix(normalized, "[]")
+	}
+	if i := strings.IndexRune(normalized, '('); i != -1 {
+		normalized = normalized[:i]
+	}
+
+	t := TypeMap.FromType(normalized)
+	switch {
+	case t == nil:
+		return slices + "string", ""
+	case t == reflect.TypeOf([]byte{}):
+		return slices + "[]byte", ""
+	case t == reflect.TypeOf(json.RawMessage{}):
+		// newer Go versions report json.RawMessage by its underlying jsontext name
+		return slices + "json.RawMessage", "encoding/json"
+	default:
+		return slices + t.String(), t.PkgPath()
+	}
+}
+
+// goInitialisms are upper cased by GoName
+var goInitialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "uid": true, "url": true, "uuid": true, "xml": true,
+}
+
+// GoName converts a postgres identifier (eg user_id) to an exported Go name (eg UserID)
+func GoName(identifier string) string {
+	words := strings.FieldsFunc(identifier, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var name strings.Builder
+	for _, word := range words {
+		switch lower := strings.ToLower(word); {
+		case goInitialisms[lower]:
+			name.WriteString(strings.ToUpper(word))
+		default:
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			name.WriteString(string(runes))
+		}
+	}
+	switch out := name.String(); {
+	case out == "":
+		return "Column"
+	case unicode.IsDigit(rune(out[0])):
+		return "X" + out
+	default:
+		return out
+	}
+}
@@ -0,0 +1,99 @@
+package pgclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+func TestGenerateStruct(t *testing.T) {
+	columns := []pgclient.Column{
+		{Name: "user_id", Type: "bigint", PrimaryKey: true, Position: 1},
+		{Name: "email", Type: "character varying(100)", Position: 2},
+		{Name: "created_at", Type: "timestamp with time zone", Nullable: true, Position: 3},
+		{Name: "tags", Type: "text[]", Nullable: true, Position: 4},
+		{Name: "payload", Type: "jsonb", Position: 5},
+		{Name: "mood", Type: "mood_enum", Position: 6},
+	}
+	src, err := pgclient.GenerateStruct("user_accounts", columns, pgclient.TemplateConfig, pgclient.GenerateOptions{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(src)
+	for _, expect := range []string{
+		"package models",
+		`"encoding/json"`,
+		`"time"`,
+		`"` + pgclient.NullImportPath + `"`,
+		"type UserAccounts struct {",
+		"UserID    int               `db:\"user_id\" pgtype:\"bigint\" primarykey:\"true\"`",
+		"Email     string            `db:\"email\" pgtype:\"character varying(100)\"`",
+		"CreatedAt null.Nullable[time.Time] `db:\"created_at\" pgtype:\"timestamp with time zone\"`",
+		"Tags      null.Nullable[[]string]",
+		"Payload   json.RawMessage",
+		"Mood      string",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(text), " "), strings.Join(strings.Fields(expect), " ")) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+}
+
+// userAccounts is what GenerateStruct writes for user_accounts, its name doesn't lowercase to the table
+type userAccounts struct {
+	UserID int64  `db:"user_id" pgtype:"bigint" primarykey:"true"`
+	Email  string `db:"email" pgtype:"text"`
+}
+
+func (userAccounts) TableName() string {
+	return "user_accounts"
+}
+
+func TestGenerateStructTableName(t *testing.T) {
+	columns := []pgclient.Column{
+		{Name: "user_id", Type: "bigint", PrimaryKey: true, Position: 1},
+		{Name: "email", Type: "text", Position: 2},
+	}
+	src, err := pgclient.GenerateStruct("user_accounts", columns, pgclient.TemplateConfig, pgclient.GenerateOptions{Name: "userAccounts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "func (userAccounts) TableName() string {\n\treturn \"user_accounts\"\n}"; !strings.Contains(string(src), expect) {
+		t.Errorf("expected %q in:\n%s", expect, src)
+	}
+
+	cfg := pgclient.TemplateConfig
+	cfg.Schema = "app"
+	for _, tpl := range []string{pgclient.PGTemplates.CreateTable, pgclient.PGTemplates.Get} {
+		text, err := pgclient.TemplateToText(userAccounts{}, tpl, &cfg, pgclient.FuncMap, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := `"app"."user_accounts"`; !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+	if schema, table, err := pgclient.TableName([]userAccounts{}, cfg); err != nil || schema != "app" || table != "user_accounts" {
+		t.Errorf("TableName: got %s.%s, %v", schema, table, err)
+	}
+
+	cfg.Table = "override"
+	if _, table, _ := pgclient.TableName(userAccounts{}, cfg); table != "override" {
+		t.Errorf("TemplatorConfig.Table should take precedence, got %s", table)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, expect := range map[string]string{
+		"user_id":    "UserID",
+		"createdAt":  "CreatedAt",
+		"api_url":    "APIURL",
+		"2fa_secret": "X2faSecret",
+		"order date": "OrderDate",
+	} {
+		if got := pgclient.GoName(in); got != expect {
+			t.Errorf("GoName(%q): got %q, expected %q", in, got, expect)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// GetOptions are per call options for Get
+type GetOptions struct {
+	Limit    int           // passed to the Get template as .rowlimit, 0 means no limit
+	Offset   int           // passed to the Get template as .rowoffset, Rows ignores it
+	PageSize int           // rows per page for Rows, defaults to DefaultPageSize
+	Example  any           // a partially populated struct, its non-zero fields are filtered with Eq, see ExampleFilters
+	Where    meta.ValueMap // Filters or values keyed by column or field name, see WhereClause
+	OrderBy  []string      // columns, optionally followed by asc or desc, see OrderClause. Rows ignores it.
+	AsOf     time.Time     // history tables only, the versions current at this time, see GetAsOf
+}
+
+// getOptions returns the first of opts, or the zero value
+func getOptions(opts []GetOptions) GetOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return GetOptions{}
+}
+
+// data returns the options as template data for value, and the named parameters of its
+// .where condition. Example filters are combined with Where.
+func (o GetOptions) data(value any, cfg client.TemplatorConfig) (map[string]any, map[string]any, error) {
+	filters := meta.ValueMap{}
+	if o.Example != nil {
+		example, err := ExampleFilters(o.Example, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		maps.Copy(filters, example)
+	}
+	maps.Copy(filters, o.Where)
+
+	where, args, err := WhereClause(value, filters, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !o.AsOf.IsZero() {
+		str, err := meta.ToStruct(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		history, err := HistoryColumnNames(str, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		asOf, err := history.AsOf("as_of")
+		if err != nil {
+			return nil, nil, err
+		}
+		if where != "" {
+			asOf = where + " and " + asOf
+		}
+		where = asOf
+		args["as_of"] = o.AsOf
+	}
+	orderby, err := OrderClause(value, o.OrderBy, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return map[string]any{
+		"rowlimit":  o.Limit,
+		"rowoffset": o.Offset,
+		"where":     where,
+		"orderby":   orderby,
+	}, args, nil
+}
+
+// Get renders the Get template for T, runs it, and scans the rows back into a []T.
+// Columns are matched to fields using FieldNameTags, then the lowercased field name.
+// Pointer fields and null.Nullable fields receive NULLs. GetOptions filter, order, and limit the rows,
+// eg GetOptions{Where: meta.ValueMap{"status": pgclient.In("new", "open")}, OrderBy: []string{"created desc"}}.
+func Get[T any](ctx context.Context, c *Client, opts ...GetOptions) ([]T, error) {
+	var zero T
+	data, args, err := getOptions(opts).data(zero, c.Config.Template)
+	if err != nil {
+		return nil, err
+	}
+	sqlText, err := c.TemplateToText(zero, c.Templator.Get, data)
+	if err != nil {
+		return nil, err
+	}
+	sqlText, params, err := BindNamed(sqlText, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.DB().Query(ctx, sqlText, params...)
+	if err != nil {
+		return nil, err
+	}
+	return CollectRows[T](rows, c.Config.Template)
+}
+
+// GetAsOf is Get for a history table (see HistoryColumnNames) at a point in time: it returns the
+// versions that were current at asOf, at most one per key
+func GetAsOf[T any](ctx context.Context, c *Client, asOf time.Time, opts ...GetOptions) ([]T, error) {
+	o := getOptions(opts)
+	o.AsOf = asOf
+	return Get[T](ctx, c, o)
+}
+
+// CollectRows scans and closes rows, returning a T for each one
+func CollectRows[T any](rows pgx.Rows, cfg client.TemplatorConfig) ([]T, error) {
+	defer rows.Close()
+
+	scan, err := newRowScanner[T](rows, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []T{}
+	for rows.Next() {
+		value, err := scan(rows)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, value)
+	}
+	return out, rows.Err()
+}
+
+// newRowScanner maps the columns in rows to T's fields and returns a function that scans the current row
+func newRowScanner[T any](rows pgx.Rows, cfg client.TemplatorConfig) (func(pgx.Rows) (T, error), error) {
+	var zero T
+	rt := reflect.TypeOf(zero)
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot scan rows into %T, it must be a struct", zero)
+	}
+
+	index, err := FieldIndexes(zero, cfg)
+	if err != nil {
+		return nil, err
+	}
+	columns, json := scanColumns(rows.FieldDescriptions(), index)
+
+	return func(rows pgx.Rows) (T, error) {
+		var value T
+		err := rows.Scan(scanTargets(reflect.ValueOf(&value).Elem(), columns, json)...)
+		return value, err
+	}, nil
+}
+
+// scanColumns returns the field index (see FieldIndexes) of each column, nil when there's no
+// matching field, and whether each column is json
+func scanColumns(fds []pgconn.FieldDescription, index map[string][]int) ([][]int, []bool) {
+	var columns [][]int
+	var json []bool
+	for _, fd := range fds {
+		columns = append(columns, index[strings.ToLower(fd.Name)])
+		json = append(json, fd.DataTypeOID == pgtype.JSONOID || fd.DataTypeOID == pgtype.JSONBOID)
+	}
+	return columns, json
+}
+
+// scanTargets returns the scan targets for the columns in the addressable struct rv
+func scanTargets(rv reflect.Value, columns [][]int, json []bool) []any {
+	targets := make([]any, len(columns))
+	for i, idx := range columns {
+		switch {
+		case idx == nil:
+			// no matching field, discard the column
+			targets[i] = new(any)
+		case json[i]:
+			// json scans through json.Unmarshal, which null.Nullable supports
+			targets[i] = rv.FieldByIndex(idx).Addr().Interface()
+		default:
+			targets[i] = NullableTarget(rv.FieldByIndex(idx).Addr().Interface())
+		}
+	}
+	return targets
+}
+
+// FieldIndexes maps lowercased column names to the reflect field index of value's fields.
+// Tag names (FieldNameTags) take precedence over lowercased field names.
+func FieldIndexes(value any, cfg client.TemplatorConfig) (map[string][]int, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := ColumnFields(str, cfg)
+
+	index := map[string][]int{}
+	for _, field := range fields {
+		index[strings.ToLower(field.Name)] = field.StructField.Index
+	}
+	for _, field := range fields {
+		index[strings.ToLower(field.TagName(cfg.FieldNameTags))] = field.StructField.Index
+	}
+	return index, nil
+}
@@ -0,0 +1,91 @@
+package pgclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+func TestFieldIndexes(t *testing.T) {
+	index, err := pgclient.FieldIndexes(structTest, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := reflect.TypeOf(structTest)
+	for column, field := range map[string]string{
+		"_id_hash":     "IDHash",
+		"idhash":       "IDHash",
+		"string_field": "StringField",
+		"time_field":   "TimeField",
+	} {
+		idx, ok := index[column]
+		if !ok {
+			t.Errorf("missing column %s", column)
+			continue
+		}
+		if name := rt.FieldByIndex(idx).Name; name != field {
+			t.Errorf("column %s: got field %s, expected %s", column, name, field)
+		}
+	}
+}
+
+func TestDefaultGetText(t *testing.T) {
+	get, err := pgclient.DefaultGetText(structTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(get, ")") {
+		t.Errorf("unexpected parenthesis in get:\n%s", get)
+	}
+}
+
+func TestGetPageText(t *testing.T) {
+	for _, after := range []bool{false, true} {
+		page, err := pgclient.TemplateToText(structTest, pgclient.PGTemplates.GetPage, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+			"after":    after,
+			"pagesize": 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nq := pgclient.ParseNamed(page)
+		switch {
+		case after && !reflect.DeepEqual(nq.Names, []string{"_id_hash"}):
+			t.Errorf("expected :_id_hash parameter, got %v:\n%s", nq.Names, page)
+		case !after && len(nq.Names) > 0:
+			t.Errorf("unexpected parameters %v:\n%s", nq.Names, page)
+		case !strings.Contains(page, `order by "_id_hash"`) || !strings.Contains(page, "limit 10"):
+			t.Errorf("missing order by or limit:\n%s", page)
+		}
+	}
+}
+
+func TestGetPageHistoryText(t *testing.T) {
+	str, err := meta.ToStruct(historyTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := pgclient.TemplateConfig
+	if got := pgclient.ColumnNames(pgclient.PrimaryKeyFields(str, cfg), cfg); !reflect.DeepEqual(got, []string{"id", "valid_from"}) {
+		t.Errorf("PrimaryKeyFields: got %v", got)
+	}
+
+	page, err := pgclient.TemplateToText(historyTest{}, pgclient.PGTemplates.GetPage, &cfg, pgclient.FuncMap, map[string]any{
+		"after":    true,
+		"pagesize": 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nq := pgclient.ParseNamed(page); !reflect.DeepEqual(nq.Names, []string{"id", "valid_from"}) {
+		t.Errorf("expected every version to be paged by :id and :valid_from, got %v:\n%s", nq.Names, page)
+	}
+	for _, expect := range []string{`( "id", "valid_from" ) > ( :id, :valid_from )`, `order by "id", "valid_from"`} {
+		if !strings.Contains(page, expect) {
+			t.Errorf("expected %q in:\n%s", expect, page)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// History tables keep every version of a row, a type 2 slowly changing dimension. A struct is one when it
+// has a RowHashTag, ValidFromTag and ValidToTag field, and it's written with the meta.History strategy.
+// Its primary key is the PrimaryKeyTag fields, the business key, plus the ValidFromTag field. Only the
+// current version has a NULL valid to, so that field should be nullable, eg *time.Time.
+
+// HistoryColumns are the column names of a history table's key and history fields
+type HistoryColumns struct {
+	Key       []string // the business key, PrimaryKeyTag fields other than ValidFrom
+	RowHash   string
+	ValidFrom string
+	ValidTo   string
+}
+
+// HistoryColumnNames returns str's HistoryColumns, or an error unless it's a history table with a business key
+func HistoryColumnNames(str meta.Struct, cfg client.TemplatorConfig) (HistoryColumns, error) {
+	fields := ColumnFields(str, cfg)
+	first := func(tag string) string {
+		if tag == "" {
+			return ""
+		}
+		if names := ColumnNames(fields.WithTagTrue(tag), cfg); len(names) > 0 {
+			return names[0]
+		}
+		return ""
+	}
+
+	h := HistoryColumns{
+		Key:       ColumnNames(fields.WithTagTrue(cfg.PrimaryKeyTag).WithoutTagTrue(cfg.ValidFromTag), cfg),
+		RowHash:   first(cfg.RowHashTag),
+		ValidFrom: first(cfg.ValidFromTag),
+		ValidTo:   first(cfg.ValidToTag),
+	}
+	switch {
+	case h.RowHash == "" || h.ValidFrom == "" || h.ValidTo == "":
+		return h, fmt.Errorf("%s is not a history table, it needs %s, %s and %s fields", str.Name, cfg.RowHashTag, cfg.ValidFromTag, cfg.ValidToTag)
+	case len(h.Key) == 0:
+		return h, fmt.Errorf("%s is not a history table, it needs a %s field", str.Name, cfg.PrimaryKeyTag)
+	}
+	return h, nil
+}
+
+// AsOf returns a condition matching the versions current at the time in the named parameter param
+func (h HistoryColumns) AsOf(param string) (string, error) {
+	from, err := QuoteIdentifier(h.ValidFrom)
+	if err != nil {
+		return "", err
+	}
+	to, err := QuoteIdentifier(h.ValidTo)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%[1]s <= :%[3]s and ( %[2]s is null or %[2]s > :%[3]s )", from, to, param), nil
+}
+
+// HashFields returns the fields RowHash covers: the ColumnFields other than the primary key, the history
+// fields, GeneratedTag, BatchIDTag and ManagedFields, since none of them are part of a row's content
+func HashFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	return ColumnFields(str, cfg).
+		WithoutTagTrue(cfg.PrimaryKeyTag, cfg.RowHashTag, cfg.ValidFromTag, cfg.ValidToTag, cfg.GeneratedTag, cfg.BatchIDTag).
+		WithoutTagTrue(managedTags(cfg))
+}
+
+// RowHash returns the meta.ValueMap.Hash of row's HashFields, NamedArgs writes it to RowHashTag fields
+func RowHash(row any, cfg client.TemplatorConfig) (string, error) {
+	str, err := meta.ToStruct(row)
+	if err != nil {
+		return "", err
+	}
+	return rowHash(str, cfg)
+}
+
+func rowHash(str meta.Struct, cfg client.TemplatorConfig) (hash string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hashing %s: %v", str.Name, r)
+		}
+	}()
+	return meta.ToValueMap(HashFields(str, cfg), "").Hash(), nil
+}
+
+// HistoryConflictError is returned by Load when a meta.History write changes a row whose current version
+// isn't older than PutOptions.ValidFrom, it would be closed before it started. Nothing is written.
+// It matches ErrConflict.
+type HistoryConflictError struct {
+	Table     string         // the quoted table, see TableIdentifier
+	Key       map[string]any // the row's business key values, by column name
+	ValidFrom time.Time      // the version being written, zero for now()
+	Current   time.Time      // the current version's valid from
+}
+
+func (e *HistoryConflictError) Error() string {
+	validFrom := "now()"
+	if !e.ValidFrom.IsZero() {
+		validFrom = e.ValidFrom.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s on %s %v: valid from %s is not after the current version's %s",
+		ErrConflict, e.Table, e.Key, validFrom, e.Current.Format(time.RFC3339Nano))
+}
+
+func (e *HistoryConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// historyConflicts runs the HistoryConflicts template against the loaded temp table and returns a
+// HistoryConflictError for each row, joined with errors.Join
+func (c *Client) historyConflicts(ctx context.Context, value any, str meta.Struct, validFrom time.Time) error {
+	history, err := HistoryColumnNames(str, c.Config.Template)
+	if err != nil {
+		return err
+	}
+	sqlText, err := c.TemplateToText(value, c.Templator.HistoryConflicts, map[string]any{"validfrom": historyTime(validFrom)})
+	if err != nil {
+		return err
+	}
+	table, err := TableIdentifier(&str, c.Config.Template)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB().Query(ctx, sqlText)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var conflicts []error
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		key := map[string]any{}
+		for i, column := range history.Key {
+			key[column] = values[i]
+		}
+		current, _ := values[len(history.Key)].(time.Time)
+		conflicts = append(conflicts, &HistoryConflictError{Table: table, Key: key, ValidFrom: validFrom, Current: current})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return errors.Join(conflicts...)
+}
+
+// historyTime renders PutOptions.ValidFrom for the PutTempToTableHistory template, now() when it's zero
+func historyTime(t time.Time) string {
+	if t.IsZero() {
+		return "now()"
+	}
+	return QuoteLiteral(t.Format(time.RFC3339Nano)) + "::timestamptz"
+}
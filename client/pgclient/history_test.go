@@ -0,0 +1,186 @@
+package pgclient_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type historyTest struct {
+	ID        string     `db:"id" primarykey:"true"`
+	Name      string     `db:"name"`
+	Score     int        `db:"score"`
+	RowHash   string     `db:"row_hash" rowhash:"true"`
+	ValidFrom time.Time  `db:"valid_from" validfrom:"true"`
+	ValidTo   *time.Time `db:"valid_to" validto:"true"`
+}
+
+func TestHistoryColumnNames(t *testing.T) {
+	str, err := meta.ToStruct(historyTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := pgclient.HistoryColumnNames(str, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := pgclient.HistoryColumns{Key: []string{"id"}, RowHash: "row_hash", ValidFrom: "valid_from", ValidTo: "valid_to"}
+	if !reflect.DeepEqual(history, expect) {
+		t.Errorf("got %+v, expected %+v", history, expect)
+	}
+
+	str, err = meta.ToStruct(structTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pgclient.HistoryColumnNames(str, pgclient.TemplateConfig); err == nil {
+		t.Error("expected an error for a struct without history fields")
+	}
+}
+
+func TestRowHash(t *testing.T) {
+	now := time.Now()
+	base := historyTest{ID: "a", Name: "x", Score: 1}
+	hash, err := pgclient.RowHash(base, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCases = []struct {
+		Name    string
+		Row     historyTest
+		Changed bool
+	}{
+		{Name: "same", Row: base},
+		{Name: "other key", Row: historyTest{ID: "b", Name: "x", Score: 1}},
+		{Name: "history fields", Row: historyTest{ID: "a", Name: "x", Score: 1, RowHash: "old", ValidFrom: now, ValidTo: &now}},
+		{Name: "name", Row: historyTest{ID: "a", Name: "y", Score: 1}, Changed: true},
+		{Name: "score", Row: historyTest{ID: "a", Name: "x", Score: 2}, Changed: true},
+	}
+	for _, v := range testCases {
+		got, err := pgclient.RowHash(v.Row, pgclient.TemplateConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got != hash) != v.Changed {
+			t.Errorf("%s: got %s, base %s, expected changed %v", v.Name, got, hash, v.Changed)
+		}
+	}
+
+	args, err := pgclient.NamedArgs(historyTest{ID: "a", Name: "x", Score: 1, RowHash: "stale"}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["row_hash"] != hash {
+		t.Errorf("NamedArgs: got row_hash %v, expected %s", args["row_hash"], hash)
+	}
+}
+
+func TestHistoryText(t *testing.T) {
+	create, err := pgclient.DefaultCreateTableText(historyTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `PRIMARY KEY ( "id", "valid_from" )`; !strings.Contains(create, expect) {
+		t.Errorf("expected %q in:\n%s", expect, create)
+	}
+
+	indexes, err := pgclient.DefaultCreateIndexesText(historyTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `create unique index if not exists "historytest_id_current_key" on "public"."historytest" ( "id" ) where "valid_to" is null;`; !strings.Contains(indexes, expect) {
+		t.Errorf("expected %q in:\n%s", expect, indexes)
+	}
+
+	text, err := pgclient.TemplateToText(historyTest{}, pgclient.PGTemplates.PutTempToTableStrategy(meta.History), &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+		"validfrom": `'2024-01-02T00:00:00Z'::timestamptz`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`set "valid_to" = '2024-01-02T00:00:00Z'::timestamptz`,
+		`and dst."valid_from" < '2024-01-02T00:00:00Z'::timestamptz`,
+		`and dst."row_hash" is distinct from src."row_hash";`,
+		`insert into "public"."historytest" ( "id", "name", "score", "row_hash", "valid_from" )`,
+		`select src."id", src."name", src."score", src."row_hash", '2024-01-02T00:00:00Z'::timestamptz`,
+		`and cur."row_hash" = src."row_hash"`,
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+}
+
+func TestHistoryConflicts(t *testing.T) {
+	text, err := pgclient.TemplateToText(historyTest{}, pgclient.PGTemplates.HistoryConflicts, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+		"validfrom": `'2024-01-02T00:00:00Z'::timestamptz`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`select tmp."id", dst."valid_from"`,
+		`order by "id", "_staged" desc`,
+		`where dst."valid_to" is null`,
+		`and dst."valid_from" >= '2024-01-02T00:00:00Z'::timestamptz`,
+		`and dst."row_hash" is distinct from tmp."row_hash"`,
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	conflict := &pgclient.HistoryConflictError{Table: `"public"."historytest"`, Key: map[string]any{"id": "a"}, ValidFrom: at, Current: at}
+	if !errors.Is(errors.Join(conflict), pgclient.ErrConflict) {
+		t.Errorf("expected %v to match ErrConflict", conflict)
+	}
+	if expect := `version conflict on "public"."historytest" map[id:a]: valid from 2024-01-02T00:00:00Z is not after the current version's 2024-01-02T00:00:00Z`; conflict.Error() != expect {
+		t.Errorf("got %s, expected %s", conflict.Error(), expect)
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	str, err := meta.ToStruct(historyTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := pgclient.HistoryColumnNames(str, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, err := history.AsOf("as_of")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `"valid_from" <= :as_of and ( "valid_to" is null or "valid_to" > :as_of )`; where != expect {
+		t.Errorf("got %s, expected %s", where, expect)
+	}
+
+	get, err := pgclient.TemplateToText(historyTest{}, pgclient.PGTemplates.Get, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{"where": where})
+	if err != nil {
+		t.Fatal(err)
+	}
+	asOf := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if _, params, err := pgclient.BindNamed(get, map[string]any{"as_of": asOf}); err != nil || !reflect.DeepEqual(params, []any{asOf}) {
+		t.Errorf("bind: got %v, %v", params, err)
+	}
+}
+
+func TestHistoryRequiresHistoryTable(t *testing.T) {
+	c := pgclient.NewClient()
+	if _, err := c.Put(context.Background(), []StructTest{structTest}, pgclient.PutOptions{Strategy: meta.History}); err == nil || !strings.Contains(err.Error(), "not a history table") {
+		t.Errorf("expected a history table error, got %v", err)
+	}
+	if _, err := pgclient.GetAsOf[StructTest](context.Background(), &c, time.Now()); err == nil || !strings.Contains(err.Error(), "not a history table") {
+		t.Errorf("expected a history table error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pgclient
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// IndexMethods are the index tag values treated as methods rather than group names
+var IndexMethods = []string{"btree", "hash", "gist", "spgist", "gin", "brin"}
+
+// Index is a secondary or unique index declared in field tags
+type Index struct {
+	Name    string
+	Table   string // schema qualified
+	Columns []string
+	Method  string // empty uses the server default, btree
+	Unique  bool
+	Where   string // partial index predicate
+}
+
+// Indexes returns the indexes declared by cfg.IndexTag and cfg.UniqueTag, in field order. Fields
+// sharing a group name (eg `unique:"grp1"`) form one multi-column index, otherwise each field gets its
+// own. Index tags may also name a method, eg `index:"gin"` or `index:"grp1,brin"`. cfg.IndexWhereTag
+// makes the field's indexes partial; tag values can't contain commas. History tables (see HistoryColumnNames)
+// also get a unique index on their business key where valid to is NULL, so each key has one current version.
+func Indexes(str *meta.Struct, cfg client.TemplatorConfig) []Index {
+	table := strings.ToLower(str.TagIdentifier(cfg.TableNameTags))
+	prefix := strings.ToLower(str.TagName(cfg.TableNameTags))
+
+	var indexes []*Index
+	groups := map[string]*Index{}
+	add := func(unique bool, group, column, method, where string) {
+		key := group
+		if unique {
+			key = "unique:" + group
+		}
+		index, ok := groups[key]
+		if !ok {
+			index = &Index{Table: table, Unique: unique}
+			groups[key] = index
+			indexes = append(indexes, index)
+		}
+		index.Columns = append(index.Columns, column)
+		if index.Method == "" {
+			index.Method = method
+		}
+		if index.Where == "" {
+			index.Where = where
+		}
+	}
+
+	for _, field := range ColumnFields(*str, cfg) {
+		column := strings.ToLower(field.TagName(cfg.FieldNameTags))
+		where := field.NonEmptyTagValue(cfg.IndexWhereTag)
+		for _, tag := range []struct {
+			key    string
+			unique bool
+		}{
+			{cfg.IndexTag, false},
+			{cfg.UniqueTag, true},
+		} {
+			if tag.key == "" || !field.HasTagTrue(tag.key) {
+				continue
+			}
+			group, method := column, ""
+			for _, v := range field.Tag(tag.key) {
+				switch v = strings.ToLower(strings.TrimSpace(v)); {
+				case slices.Contains(IndexMethods, v):
+					method = v
+				case v != "" && v != "true":
+					group = v
+				}
+			}
+			add(tag.unique, group, column, method, where)
+		}
+	}
+
+	if history, err := HistoryColumnNames(*str, cfg); err == nil {
+		if to, err := QuoteIdentifier(history.ValidTo); err == nil {
+			indexes = append(indexes, &Index{
+				Name:    truncateIdentifier(prefix + "_" + strings.Join(history.Key, "_") + "_current_key"),
+				Table:   table,
+				Columns: history.Key,
+				Unique:  true,
+				Where:   to + " is null",
+			})
+		}
+	}
+
+	out := []Index{}
+	for _, index := range indexes {
+		suffix := "_idx"
+		if index.Unique {
+			suffix = "_key"
+		}
+		if index.Name == "" {
+			index.Name = truncateIdentifier(prefix + "_" + strings.Join(index.Columns, "_") + suffix)
+		}
+		out = append(out, *index)
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package pgclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type indexTest struct {
+	ID        int64    `db:"id" primarykey:"true"`
+	TenantID  int64    `db:"tenant_id" unique:"tenant_email"`
+	Email     string   `db:"email" unique:"tenant_email" index:"true"`
+	Tags      []string `db:"tags" pgtype:"text[]" index:"gin"`
+	CreatedAt string   `db:"created_at" index:"brin"`
+	DeletedAt string   `db:"deleted_at" unique:"true" indexwhere:"deleted_at is not null"`
+}
+
+func TestCreateIndexesText(t *testing.T) {
+	text, err := pgclient.DefaultCreateIndexesText(indexTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := `"` + pgclient.TemplateConfig.Schema + `"."indextest"`
+	expected := []string{
+		`create unique index if not exists "indextest_tenant_id_email_key" on ` + table + ` ( "tenant_id", "email" );`,
+		`create index if not exists "indextest_email_idx" on ` + table + ` ( "email" );`,
+		`create index if not exists "indextest_tags_idx" on ` + table + ` using gin ( "tags" );`,
+		`create index if not exists "indextest_created_at_idx" on ` + table + ` using brin ( "created_at" );`,
+		`create unique index if not exists "indextest_deleted_at_key" on ` + table + ` ( "deleted_at" ) where deleted_at is not null;`,
+	}
+	var statements []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			statements = append(statements, line)
+		}
+	}
+	if len(statements) != len(expected) {
+		t.Fatalf("expected %d statements, got %d:\n%s", len(expected), len(statements), text)
+	}
+	for i, expect := range expected {
+		if statements[i] != expect {
+			t.Errorf("statement %d:\ngot      %s\nexpected %s", i, statements[i], expect)
+		}
+	}
+
+	none, err := pgclient.DefaultCreateIndexesText(structTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(none) != "" {
+		t.Errorf("expected no indexes, got:\n%s", none)
+	}
+}
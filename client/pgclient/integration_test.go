@@ -0,0 +1,398 @@
+package pgclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// The tests in this file run against a real database, they're skipped unless PGCLIENT_TEST_DSN is set,
+// eg PGCLIENT_TEST_DSN=postgres://postgres@localhost/postgres go test ./client/pgclient/
+// Each run works in its own schema, which is dropped afterwards.
+
+const testDSN = "PGCLIENT_TEST_DSN"
+
+type integrationItem struct {
+	ID    int64  `db:"id" primarykey:"true"`
+	Name  string `db:"name"`
+	Score int    `db:"score"`
+}
+
+// integrationClient connects to PGCLIENT_TEST_DSN with a new schema, skipping the test when it isn't set
+func integrationClient(t *testing.T) *pgclient.Client {
+	t.Helper()
+	dsn := os.Getenv(testDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSN)
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("pgclient_test_%d", time.Now().UnixNano())
+	c := pgclient.NewClient(client.Config{Connection: client.ConnectionConfig{ConnectionString: dsn, Schema: schema}})
+	if err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := c.DB().Exec(ctx, "drop schema if exists "+pgx.Identifier{schema}.Sanitize()+" cascade"); err != nil {
+			t.Error(err)
+		}
+		c.Close(ctx)
+	})
+	if err := c.CreateSchema(ctx, integrationItem{}); err != nil {
+		t.Fatal(err)
+	}
+	return &c
+}
+
+// tableClient returns a copy of c writing to table, creating it for value
+func tableClient(t *testing.T, c *pgclient.Client, table string, value any) *pgclient.Client {
+	t.Helper()
+	tc := *c
+	tc.Config.Template.Table = table
+	if err := tc.CreateTable(context.Background(), value); err != nil {
+		t.Fatal(err)
+	}
+	return &tc
+}
+
+func TestIntegrationUpdateStrategies(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	existing := []integrationItem{{1, "a", 1}, {2, "b", 2}}
+
+	var testCases = []struct {
+		Strategy meta.UpdateStrategy
+		Write    []integrationItem
+		Expect   []integrationItem
+	}{
+		{
+			Strategy: meta.AppendChanges,
+			Write:    []integrationItem{{2, "b2", 20}, {3, "c", 3}},
+			Expect:   []integrationItem{{1, "a", 1}, {2, "b", 2}, {3, "c", 3}},
+		},
+		{
+			// every row is inserted, so it only takes new keys
+			Strategy: meta.AppendAll,
+			Write:    []integrationItem{{3, "c", 3}, {4, "d", 4}},
+			Expect:   []integrationItem{{1, "a", 1}, {2, "b", 2}, {3, "c", 3}, {4, "d", 4}},
+		},
+		{
+			Strategy: meta.ReplaceChanges,
+			Write:    []integrationItem{{2, "b2", 20}, {3, "c", 3}},
+			Expect:   []integrationItem{{1, "a", 1}, {2, "b2", 20}, {3, "c", 3}},
+		},
+		{
+			Strategy: meta.ReplaceAll,
+			Write:    []integrationItem{{2, "b2", 20}, {3, "c", 3}},
+			Expect:   []integrationItem{{2, "b2", 20}, {3, "c", 3}},
+		},
+	}
+
+	for _, v := range testCases {
+		for _, method := range []string{"Put", "Load"} {
+			t.Run(v.Strategy.String()+"/"+method, func(t *testing.T) {
+				tc := tableClient(t, c, strings.ToLower(method+"_"+v.Strategy.String()), integrationItem{})
+				if _, err := tc.Load(ctx, existing); err != nil {
+					t.Fatal(err)
+				}
+				write := tc.Put
+				if method == "Load" {
+					write = tc.Load
+				}
+				if _, err := write(ctx, v.Write, pgclient.PutOptions{Strategy: v.Strategy}); err != nil {
+					t.Fatal(err)
+				}
+				got, err := pgclient.Get[integrationItem](ctx, tc, pgclient.GetOptions{OrderBy: []string{"id"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, v.Expect) {
+					t.Errorf("got %v, expected %v", got, v.Expect)
+				}
+			})
+		}
+	}
+}
+
+func TestIntegrationLoadDuplicateKeys(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	for _, strategy := range []meta.UpdateStrategy{meta.AppendChanges, meta.ReplaceChanges, meta.ReplaceAll} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			tc := tableClient(t, c, "duplicates_"+strings.ToLower(strategy.String()), integrationItem{})
+			results, err := tc.Load(ctx, []integrationItem{{1, "first", 1}, {1, "last", 2}}, pgclient.PutOptions{Strategy: strategy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch := results[0].(pgclient.LoadBatch)
+			if batch.Staged != 2 || batch.Inserted != 1 || batch.Skipped != 1 {
+				t.Errorf("unexpected counts %+v", batch)
+			}
+			got, err := pgclient.Get[integrationItem](ctx, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expect := []integrationItem{{1, "last", 2}}; !reflect.DeepEqual(got, expect) {
+				t.Errorf("got %v, expected %v", got, expect)
+			}
+		})
+	}
+}
+
+func TestIntegrationHistory(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	for _, method := range []string{"Put", "Load"} {
+		t.Run(method, func(t *testing.T) {
+			tc := tableClient(t, c, "history_"+strings.ToLower(method), historyTest{})
+			write := tc.Put
+			if method == "Load" {
+				write = tc.Load
+			}
+			jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+			if _, err := write(ctx, []historyTest{{ID: "a", Name: "x", Score: 1}, {ID: "b", Name: "y", Score: 2}}, pgclient.PutOptions{Strategy: meta.History, ValidFrom: jan}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := write(ctx, []historyTest{{ID: "a", Name: "x2", Score: 1}, {ID: "b", Name: "y", Score: 2}}, pgclient.PutOptions{Strategy: meta.History, ValidFrom: feb}); err != nil {
+				t.Fatal(err)
+			}
+
+			all, err := pgclient.Get[historyTest](ctx, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 3 {
+				t.Errorf("expected 3 versions, got %+v", all)
+			}
+			for _, v := range []struct {
+				AsOf   time.Time
+				Expect map[string]string
+			}{
+				{AsOf: jan.Add(-time.Hour), Expect: map[string]string{}},
+				{AsOf: jan.Add(time.Hour), Expect: map[string]string{"a": "x", "b": "y"}},
+				{AsOf: feb.Add(time.Hour), Expect: map[string]string{"a": "x2", "b": "y"}},
+			} {
+				rows, err := pgclient.GetAsOf[historyTest](ctx, tc, v.AsOf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := map[string]string{}
+				for _, row := range rows {
+					got[row.ID] = row.Name
+				}
+				if !reflect.DeepEqual(got, v.Expect) {
+					t.Errorf("as of %s: got %v, expected %v", v.AsOf, got, v.Expect)
+				}
+			}
+
+			// a change can't take effect before the current version did
+			_, err = write(ctx, []historyTest{{ID: "a", Name: "x3", Score: 1}}, pgclient.PutOptions{Strategy: meta.History, ValidFrom: jan})
+			var conflict *pgclient.HistoryConflictError
+			if !errors.Is(err, pgclient.ErrConflict) || !errors.As(err, &conflict) || !conflict.Current.Equal(feb) {
+				t.Errorf("expected a history conflict, got %v", err)
+			}
+			if after, err := pgclient.Get[historyTest](ctx, tc); err != nil || len(after) != len(all) {
+				t.Errorf("a rejected load shouldn't write anything, got %d rows, %v", len(after), err)
+			}
+		})
+	}
+}
+
+func TestIntegrationRows(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	tc := tableClient(t, c, "rows", integrationItem{})
+	items := make([]integrationItem, 25)
+	for i := range items {
+		items[i] = integrationItem{ID: int64(i + 1), Name: fmt.Sprint(i + 1), Score: i % 3}
+	}
+	if _, err := tc.Load(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		Name   string
+		Opts   pgclient.GetOptions
+		Expect int
+	}{
+		{Name: "all", Opts: pgclient.GetOptions{PageSize: 10}, Expect: 25},
+		{Name: "exact pages", Opts: pgclient.GetOptions{PageSize: 5}, Expect: 25},
+		{Name: "limit", Opts: pgclient.GetOptions{PageSize: 10, Limit: 12}, Expect: 12},
+		{Name: "where", Opts: pgclient.GetOptions{PageSize: 4, Where: meta.ValueMap{"score": 0}}, Expect: 9},
+	} {
+		t.Run(v.Name, func(t *testing.T) {
+			var last int64
+			var count int
+			for item, err := range pgclient.Rows[integrationItem](ctx, tc, v.Opts) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if item.ID <= last {
+					t.Errorf("got id %d after %d", item.ID, last)
+				}
+				last = item.ID
+				count++
+			}
+			if count != v.Expect {
+				t.Errorf("got %d rows, expected %d", count, v.Expect)
+			}
+		})
+	}
+}
+
+func TestIntegrationLoadNested(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	orders := []nestedOrder{
+		{ID: 1, Lines: []nestedLine{{LineNo: 1, Item: "a"}, {LineNo: 2, Item: "b"}}, Notes: []*nestedNote{{Text: "x"}}},
+		{ID: 2, Lines: []nestedLine{{LineNo: 1, Item: "c"}}},
+	}
+	results, err := c.LoadNested(ctx, orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected a result per table, got %d", len(results))
+	}
+
+	lc := *c
+	lc.Config.Template.Table = "order_lines"
+	lines, err := pgclient.Get[nestedLine](ctx, &lc, pgclient.GetOptions{OrderBy: []string{"order_id", "line_no"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []nestedLine{{OrderID: 1, LineNo: 1, Item: "a"}, {OrderID: 1, LineNo: 2, Item: "b"}, {OrderID: 2, LineNo: 1, Item: "c"}}
+	if !reflect.DeepEqual(lines, expect) {
+		t.Errorf("got %+v, expected %+v", lines, expect)
+	}
+}
+
+func TestIntegrationWatermarks(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	tc := tableClient(t, c, "", watermarkTest{})
+
+	if _, ok, err := pgclient.Watermark[watermarkTest](ctx, tc, ""); err != nil || ok {
+		t.Errorf("expected no watermark for an empty table, got %v, %v", ok, err)
+	}
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := tc.Load(ctx, []watermarkTest{{ID: 1, Name: "a", UpdatedAt: jan}, {ID: 7, Name: "b", UpdatedAt: jan.Add(time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	recent, ok, err := pgclient.MostRecent[watermarkTest](ctx, tc)
+	if err != nil || !ok || recent.ID != 7 || !recent.UpdatedAt.Equal(jan.Add(time.Hour)) {
+		t.Errorf("unexpected most recent %+v, %v, %v", recent, ok, err)
+	}
+
+	if _, ok, err := pgclient.GetCheckpoint[watermarkTest](ctx, tc, "pull"); err != nil || ok {
+		t.Errorf("expected no checkpoint before one is saved, got %v, %v", ok, err)
+	}
+	saved := watermarkTest{ID: 3, UpdatedAt: jan}
+	if err := tc.SaveCheckpoint(ctx, "pull", saved); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := pgclient.Watermark[watermarkTest](ctx, tc, "pull")
+	if err != nil || !ok || got.ID != saved.ID || !got.UpdatedAt.Equal(saved.UpdatedAt) {
+		t.Errorf("expected the saved checkpoint, got %+v, %v, %v", got, ok, err)
+	}
+
+	if _, _, err := pgclient.CheckpointMostRecent[watermarkTest](ctx, tc, "pull"); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err = pgclient.GetCheckpoint[watermarkTest](ctx, tc, "pull")
+	if err != nil || !ok || got.ID != 7 {
+		t.Errorf("expected the checkpoint to be replaced, got %+v, %v, %v", got, ok, err)
+	}
+}
+
+func TestIntegrationLoadBatchTable(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	tc := tableClient(t, c, "batched", integrationItem{})
+	tc.Config.Template.BatchTable = "load_batches"
+
+	results, err := tc.Load(ctx, []integrationItem{{1, "a", 1}, {2, "b", 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := results[0].(pgclient.LoadBatch)
+	missing := *tc
+	missing.Config.Template.Table = "missing"
+	if _, err := missing.Load(ctx, []integrationItem{{3, "c", 3}}); err == nil {
+		t.Fatal("expected an error loading a table that doesn't exist")
+	}
+
+	bc := *c
+	bc.Config.Template.Table = "load_batches"
+	batches, err := pgclient.Get[pgclient.LoadBatch](ctx, &bc, pgclient.GetOptions{OrderBy: []string{"started_at"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %+v", batches)
+	}
+	if ok := batches[0]; ok.ID != loaded.ID || ok.Staged != 2 || ok.Inserted != 2 || ok.FinishedAt == nil || ok.Error != nil {
+		t.Errorf("unexpected batch %+v", ok)
+	}
+	if failed := batches[1]; failed.Error == nil || failed.FinishedAt == nil || failed.Inserted != 0 {
+		t.Errorf("expected the failed load to be recorded, got %+v", failed)
+	}
+}
+
+type versionedItem struct {
+	ID      int64  `db:"id" primarykey:"true"`
+	Name    string `db:"name"`
+	Version int    `db:"version" version:"true"`
+}
+
+func TestIntegrationVersionConflicts(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+	for _, method := range []string{"Put", "Load"} {
+		t.Run(method, func(t *testing.T) {
+			tc := tableClient(t, c, "versioned_"+strings.ToLower(method), versionedItem{})
+			write := tc.Put
+			if method == "Load" {
+				write = tc.Load
+			}
+			for _, v := range []struct {
+				Name     string
+				Row      versionedItem
+				Conflict *pgclient.ConflictError
+				Expect   versionedItem
+			}{
+				{Name: "new key", Row: versionedItem{1, "a", 0}, Expect: versionedItem{1, "a", 1}},
+				{Name: "zero version on an existing key", Row: versionedItem{1, "b", 0}, Conflict: &pgclient.ConflictError{Version: 0, Current: 1}, Expect: versionedItem{1, "a", 1}},
+				{Name: "current version", Row: versionedItem{1, "b", 1}, Expect: versionedItem{1, "b", 2}},
+				{Name: "stale version", Row: versionedItem{1, "c", 1}, Conflict: &pgclient.ConflictError{Version: 1, Current: 2}, Expect: versionedItem{1, "b", 2}},
+			} {
+				_, err := write(ctx, []versionedItem{v.Row}, pgclient.PutOptions{Strategy: meta.ReplaceChanges})
+				var conflict *pgclient.ConflictError
+				switch {
+				case v.Conflict == nil && err != nil:
+					t.Fatalf("%s: %v", v.Name, err)
+				case v.Conflict != nil && (!errors.As(err, &conflict) || conflict.Version != v.Conflict.Version || conflict.Current != v.Conflict.Current):
+					t.Errorf("%s: expected a conflict like %v, got %v", v.Name, v.Conflict, err)
+				}
+				got, err := pgclient.Get[versionedItem](ctx, tc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expect := []versionedItem{v.Expect}; !reflect.DeepEqual(got, expect) {
+					t.Errorf("%s: got %v, expected %v", v.Name, got, expect)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// Load bulk loads value (a struct or slice of structs) through a temp table in a single transaction (see WithTx):
+// CreateTempTable, COPY the rows into it, PutTempToTable, and DropTempTable. It is much faster
+// than Put for large slices. The PutTempToTable template is chosen by the update strategy. The temp table
+// numbers rows in the order they're staged, so when value has duplicate keys the last one wins.
+//
+// With meta.ReplaceChanges or meta.ReplaceAll and a VersionTag field, the temp table is checked with the
+// VersionConflicts template first, and any mismatch is returned as ConflictErrors, joined with errors.Join,
+// without writing anything. As with Put, a zero version conflicts with any existing row with its key.
+//
+// With meta.History, value must be a history table (see HistoryColumnNames). The current version of each
+// row whose RowHash changed gets PutOptions.ValidFrom as its valid to, and the new version is inserted
+// with it as its valid from. New keys are inserted, unchanged rows and keys missing from value are left as is.
+// A changed row whose current version isn't older than ValidFrom can't be closed, so the load is rejected
+// with a HistoryConflictError for each one, without writing anything.
+//
+// The only result is a LoadBatch with the call's counts. When TemplatorConfig.BatchTable is set it is
+// also recorded there, which costs a round trip before and after the load. Inside a surrounding
+// transaction the record of a failed load is only kept if that transaction commits.
+func (c *Client) Load(ctx context.Context, value any, opts ...PutOptions) (meta.SQLResults, error) {
+	o := c.putOptions(opts)
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(str.Data) == 0 {
+		return nil, nil
+	}
+	if o.Strategy == meta.History {
+		if _, err := HistoryColumnNames(str, c.Config.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	batch := newLoadBatch(str, c, o.Strategy)
+	tracked := c.Config.Template.BatchTable != ""
+	if tracked {
+		if err := c.putMetadata(ctx, c.Config.Template.BatchTable, &batch); err != nil {
+			return nil, fmt.Errorf("recording load batch: %w", err)
+		}
+	}
+
+	err = c.WithTx(ctx, func(tx *Client) error {
+		if _, err := tx.Exec(ctx, tx.Templator.CreateTempTable, value); err != nil {
+			return err
+		}
+		staged, err := CopyToTempTable(ctx, tx.DB(), str, tx.Config.Template)
+		if err != nil {
+			return err
+		}
+		batch.Staged = staged
+		if o.Strategy == meta.ReplaceChanges || o.Strategy == meta.ReplaceAll {
+			if err := tx.tempVersionConflicts(ctx, value, str); err != nil {
+				return err
+			}
+		}
+		if o.Strategy == meta.History {
+			if err := tx.historyConflicts(ctx, value, str, o.ValidFrom); err != nil {
+				return err
+			}
+		}
+		sqlText, err := tx.TemplateToText(value, tx.Templator.PutTempToTableStrategy(o.Strategy), map[string]any{
+			"validfrom":    historyTime(o.ValidFrom),
+			"countinserts": true,
+		})
+		if err != nil {
+			return err
+		}
+		results, err := tx.Tx.Conn().PgConn().Exec(ctx, sqlText).ReadAll()
+		if err != nil {
+			return err
+		}
+		inserted, updated, err := loadCounts(results, o.Strategy)
+		if err != nil {
+			return err
+		}
+		batch.Inserted, batch.Updated = inserted, updated
+		_, err = tx.Exec(ctx, tx.Templator.DropTempTable, value)
+		return err
+	})
+
+	batch.finish(err)
+	if tracked {
+		if recordErr := c.putMetadata(ctx, c.Config.Template.BatchTable, &batch); recordErr != nil {
+			err = errors.Join(err, fmt.Errorf("recording load batch: %w", recordErr))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return meta.SQLResults{batch}, nil
+}
+
+// TempTableName returns the name CreateTempTable gives the temp table for str, truncated
+// to MaxIdentifierLength as postgres would
+func TempTableName(str meta.Struct, cfg client.TemplatorConfig) string {
+	ConfigureStruct(&str, cfg)
+	return truncateIdentifier("_tmp_" + strings.ToLower(str.TagName(cfg.TableNameTags)))
+}
+
+// CopyToTempTable streams str.Data into its temp table with COPY, using the WriteFields ColumnNames for the column list.
+// Zero valued DefaultFields are written as NULL, the PutTempToTable templates fill them in (see TempSelect),
+// and BatchIDTag fields are set to str.UUID, the LoadBatch ID.
+func CopyToTempTable(ctx context.Context, tx DB, str meta.Struct, cfg client.TemplatorConfig) (int64, error) {
+	fields := WriteFields(str, cfg)
+	columns := ColumnNames(fields, cfg)
+	defaultFields := DefaultFields(str, cfg)
+	var batchColumns []string
+	if cfg.BatchIDTag != "" {
+		batchColumns = ColumnNames(fields.WithTagTrue(cfg.BatchIDTag), cfg)
+	}
+	rows := pgx.CopyFromSlice(len(str.Data), func(i int) ([]any, error) {
+		args, err := NamedArgs(str.Data[i], cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range ZeroDefaults(str.Data[i], defaultFields, cfg) {
+			args[column] = nil
+		}
+		for _, column := range batchColumns {
+			args[column] = str.UUID
+		}
+		values := make([]any, len(columns))
+		for j, column := range columns {
+			values[j] = args[column]
+		}
+		return values, nil
+	})
+	return tx.CopyFrom(ctx, pgx.Identifier{TempTableName(str, cfg)}, columns, rows)
+}
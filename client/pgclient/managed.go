@@ -0,0 +1,158 @@
+package pgclient
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// Managed columns are filled in by the database rather than the caller: IdentityTag, CreatedAtTag,
+// UpdatedAtTag and VersionTag fields. They are DefaultFields, so zero values are written as DEFAULT,
+// and upserts never overwrite them from the new row (see UpdateFields).
+
+// ManagedFields returns the fields tagged with IdentityTag, CreatedAtTag, UpdatedAtTag or VersionTag
+func ManagedFields(fields meta.Fields, cfg client.TemplatorConfig) meta.Fields {
+	return fields.WithTagTrue(managedTags(cfg))
+}
+
+func managedTags(cfg client.TemplatorConfig) []string {
+	var tags []string
+	for _, tag := range []string{cfg.IdentityTag, cfg.CreatedAtTag, cfg.UpdatedAtTag, cfg.VersionTag} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// AlwaysIdentity returns true for GENERATED ALWAYS identity fields, tagged `identity:"always"`.
+// The database rejects values for them, so they are left out of every insert.
+func AlwaysIdentity(field meta.Field, cfg client.TemplatorConfig) bool {
+	return cfg.IdentityTag != "" && field.HasTagValue(cfg.IdentityTag, "always")
+}
+
+// CopyNullFields returns the ColumnFields CopyToTempTable may leave NULL: DefaultFields, written as NULL
+// when they're zero, and AlwaysIdentity fields, which aren't written. CreateTempTable drops their NOT NULL.
+func CopyNullFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	defaults := map[string]bool{}
+	for _, field := range DefaultFields(str, cfg) {
+		defaults[field.Name] = true
+	}
+	var fields meta.Fields
+	for _, field := range ColumnFields(str, cfg) {
+		if defaults[field.Name] || AlwaysIdentity(field, cfg) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// ManagedDefault returns the default CreateTable gives a managed column without a DefaultTag:
+// now() for CreatedAtTag and UpdatedAtTag fields, 1 for VersionTag fields, and nothing otherwise
+func ManagedDefault(field meta.Field, cfg client.TemplatorConfig) string {
+	switch {
+	case cfg.CreatedAtTag != "" && field.HasTagTrue(cfg.CreatedAtTag),
+		cfg.UpdatedAtTag != "" && field.HasTagTrue(cfg.UpdatedAtTag):
+		return "now()"
+	case cfg.VersionTag != "" && field.HasTagTrue(cfg.VersionTag):
+		return "1"
+	default:
+		return ""
+	}
+}
+
+// UpdateFields returns the fields an upsert compares and sets from the new row: all but the primary key,
+// the ManagedFields and BatchIDTag fields. UpdatedAtTag columns are set to now() and VersionTag columns
+// incremented instead, BatchIDTag columns are set without being compared.
+func UpdateFields(fields meta.Fields, cfg client.TemplatorConfig) meta.Fields {
+	return fields.WithoutTagTrue(cfg.PrimaryKeyTag, cfg.BatchIDTag).WithoutTagTrue(managedTags(cfg))
+}
+
+// UpdateSet returns the assignments for an upsert's do update set: each of UpdateFields and BatchIDTag
+// fields from the new row, UpdatedAtTag columns to now(), and VersionTag columns to their current value plus one
+func UpdateSet(fields meta.Fields, cfg client.TemplatorConfig) ([]string, error) {
+	var set []string
+	assign := func(fields meta.Fields, format string) error {
+		columns, err := QuoteIdentifiers(ColumnNames(fields, cfg))
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			set = append(set, fmt.Sprintf(format, column))
+		}
+		return nil
+	}
+
+	if err := assign(UpdateFields(fields, cfg), "%[1]s = excluded.%[1]s"); err != nil {
+		return nil, err
+	}
+	if cfg.BatchIDTag != "" {
+		if err := assign(fields.WithTagTrue(cfg.BatchIDTag), "%[1]s = excluded.%[1]s"); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.UpdatedAtTag != "" {
+		if err := assign(fields.WithTagTrue(cfg.UpdatedAtTag), "%s = now()"); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.VersionTag != "" {
+		if err := assign(fields.WithTagTrue(cfg.VersionTag), "%[1]s = dst.%[1]s + 1"); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// TempSelect returns the select list the PutTempToTable templates read from the temp table. Each of
+// fields is selected as is, except DefaultFields, which CopyToTempTable writes as NULL when they're
+// zero: those fall back to their DefaultTag or ManagedDefault, or the next identity value.
+func TempSelect(str *meta.Struct, fields meta.Fields, cfg client.TemplatorConfig) ([]string, error) {
+	defaults := map[string]bool{}
+	for _, column := range ColumnNames(DefaultFields(*str, cfg), cfg) {
+		defaults[column] = true
+	}
+	table, err := TableIdentifier(str, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := ColumnNames(fields, cfg)
+	selects := make([]string, len(fields))
+	for i, field := range fields {
+		quoted, err := QuoteIdentifier(columns[i])
+		if err != nil {
+			return nil, err
+		}
+		if !defaults[columns[i]] {
+			selects[i] = quoted
+			continue
+		}
+
+		value := field.NonEmptyTagValue(cfg.DefaultTag)
+		if value != "" {
+			if _, err := SafeExpression(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", field.Name, err)
+			}
+		} else if value = ManagedDefault(field, cfg); value == "" {
+			value = fmt.Sprintf("nextval( pg_get_serial_sequence( %s, %s ) )", QuoteLiteral(table), QuoteLiteral(columns[i]))
+		}
+		selects[i] = fmt.Sprintf("coalesce( %s, %s ) as %s", quoted, value, quoted)
+	}
+	return selects, nil
+}
+
+// versionNumber returns an integer version value as an int64
+func versionNumber(value any) (int64, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,134 @@
+package pgclient_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type managedTest struct {
+	ID        int64     `db:"id" primarykey:"true" identity:"true"`
+	Name      string    `db:"name"`
+	Seq       int64     `db:"seq" identity:"always"`
+	CreatedAt time.Time `db:"created_at" createdat:"true"`
+	UpdatedAt time.Time `db:"updated_at" updatedat:"true" default:"clock_timestamp()"`
+	Version   int       `db:"version" version:"true"`
+}
+
+func TestManagedFields(t *testing.T) {
+	str, err := meta.ToStruct(managedTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := pgclient.TemplateConfig
+	for name, v := range map[string]struct {
+		Fields meta.Fields
+		Expect []string
+	}{
+		"WriteFields":     {pgclient.WriteFields(str, cfg), []string{"id", "name", "created_at", "updated_at", "version"}},
+		"DefaultFields":   {pgclient.DefaultFields(str, cfg), []string{"id", "created_at", "updated_at", "version"}},
+		"ReturningFields": {pgclient.ReturningFields(str, cfg), []string{"id", "seq", "created_at", "updated_at", "version"}},
+		"UpdateFields":    {pgclient.UpdateFields(str.Fields(), cfg), []string{"name"}},
+		"CopyNullFields":  {pgclient.CopyNullFields(str, cfg), []string{"id", "seq", "created_at", "updated_at", "version"}},
+	} {
+		if got := pgclient.ColumnNames(v.Fields, cfg); !reflect.DeepEqual(got, v.Expect) {
+			t.Errorf("%s: got %v, expected %v", name, got, v.Expect)
+		}
+	}
+}
+
+func TestManagedColumnDefinitions(t *testing.T) {
+	text, err := pgclient.DefaultCreateTableText(managedTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`"id"	bigint GENERATED BY DEFAULT AS IDENTITY,`,
+		`"seq"	bigint GENERATED ALWAYS AS IDENTITY,`,
+		`"created_at"	timestamp with time zone NOT NULL DEFAULT now(),`,
+		`"updated_at"	timestamp with time zone NOT NULL DEFAULT clock_timestamp(),`,
+		`"version"	bigint NOT NULL DEFAULT 1,`,
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+}
+
+func TestManagedUpsertText(t *testing.T) {
+	set := []string{`"name" = excluded."name"`, `"updated_at" = now()`, `"version" = dst."version" + 1`}
+	var testCases = []struct {
+		Name   string
+		Tpl    string
+		Expect []string
+	}{
+		{Name: "Put", Tpl: pgclient.PGTemplates.Put, Expect: []string{"do nothing"}},
+		{Name: "PutReplaceChanges", Tpl: pgclient.PGTemplates.PutReplaceChanges, Expect: append(set, `is distinct from ( excluded."name" ) and dst."version" = :version`)},
+		{Name: "PutTempToTableReplaceChanges", Tpl: pgclient.PGTemplates.PutTempToTableReplaceChanges, Expect: append(set, `coalesce( "version", 1 ) as "version"`)},
+		{Name: "PutTempToTableReplaceAll", Tpl: pgclient.PGTemplates.PutTempToTableReplaceAll, Expect: append(set, `coalesce( "created_at", now() ) as "created_at"`)},
+		{Name: "PutTempToTableAppendAll", Tpl: pgclient.PGTemplates.PutTempToTableAppendAll, Expect: []string{`nextval( pg_get_serial_sequence( '"public"."managedtest"', 'id' ) )`}},
+		{Name: "CreateTempTable", Tpl: pgclient.PGTemplates.CreateTempTable, Expect: []string{`alter column "seq" drop not null`, `alter column "version" drop not null`}},
+		{Name: "GetVersion", Tpl: pgclient.PGTemplates.GetVersion, Expect: []string{`select "version"`, `where ( "id" ) = ( :id )`}},
+		{Name: "VersionConflicts", Tpl: pgclient.PGTemplates.VersionConflicts, Expect: []string{`select tmp."id", tmp."version", dst."version"`, `from "_tmp_managedtest" tmp`, `where dst."version" is distinct from tmp."version"`}},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			text, err := pgclient.TemplateToText(managedTest{}, v.Tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expect := range v.Expect {
+				if !strings.Contains(text, expect) {
+					t.Errorf("expected %q in:\n%s", expect, text)
+				}
+			}
+			if v.Name != "CreateTempTable" && strings.Contains(text, `"created_at" = `) {
+				t.Errorf("created_at should never be updated:\n%s", text)
+			}
+			if strings.HasPrefix(v.Name, "Put") && strings.Contains(text, `"seq"`) {
+				t.Errorf("GENERATED ALWAYS identity seq should not be written:\n%s", text)
+			}
+		})
+	}
+}
+
+func TestManagedPutParameters(t *testing.T) {
+	text, err := pgclient.TemplateToText(managedTest{}, pgclient.PGTemplates.PutReplaceChanges, &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+		"defaults": []string{"id", "created_at", "updated_at", "version"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// defaults are written as DEFAULT, but updates compare the row's own version, even when it's zero
+	if nq := pgclient.ParseNamed(text); !reflect.DeepEqual(nq.Names, []string{"name", "version"}) {
+		t.Errorf("expected name and version to be parameters, got %v:\n%s", nq.Names, text)
+	}
+	if expect := "\tDEFAULT\n)"; !strings.Contains(text, expect) {
+		t.Errorf("expected a zero version to be inserted as DEFAULT:\n%s", text)
+	}
+}
+
+func TestConflictError(t *testing.T) {
+	conflict := &pgclient.ConflictError{Table: `"public"."managedtest"`, Key: map[string]any{"id": int64(1)}, Version: 2, Current: 3}
+	err := fmt.Errorf("put: %w", errors.Join(conflict, errors.New("other")))
+	if !errors.Is(err, pgclient.ErrConflict) {
+		t.Errorf("expected %v to match ErrConflict", err)
+	}
+	var target *pgclient.ConflictError
+	if !errors.As(err, &target) || target.Current != 3 {
+		t.Errorf("expected a ConflictError, got %v", err)
+	}
+	if expect := `version conflict on "public"."managedtest" map[id:1]: expected version 2, found 3`; conflict.Error() != expect {
+		t.Errorf("got %s, expected %s", conflict.Error(), expect)
+	}
+	if errors.Is(errors.New("version conflict"), pgclient.ErrConflict) {
+		t.Error("only ConflictErrors should match ErrConflict")
+	}
+}
@@ -0,0 +1,174 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// LoadNested persists value (a struct or slice of structs) and its ChildTag fields in one transaction.
+// Child fields must be structs or slices of structs; each gets its own table, named by its field name
+// or TableNameTags, with a foreign key from its ParentPrimaryKeyTag fields to its parent's primary key.
+// Tables are created in dependency order and parents are loaded (see Load) before their children.
+func (c *Client) LoadNested(ctx context.Context, value any, opts ...PutOptions) (meta.SQLResults, error) {
+	structs, err := Nested(value, c.Config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var results meta.SQLResults
+	err = c.WithTx(ctx, func(tx *Client) error {
+		for i, str := range structs {
+			if err := tx.nestedClient(i).CreateTable(ctx, str); err != nil {
+				return fmt.Errorf("creating table for %s: %w", str.Name, err)
+			}
+		}
+		for i, str := range structs {
+			if len(str.Data) == 0 {
+				continue
+			}
+			result, err := tx.nestedClient(i).Load(ctx, str, opts...)
+			if err != nil {
+				return fmt.Errorf("loading %s: %w", str.Name, err)
+			}
+			results = append(results, result...)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// NestedText renders tpl for each struct from Nested, with the config LoadNested uses for it
+func (c *Client) NestedText(value any, tpl string, data ...map[string]any) ([]string, error) {
+	structs, err := Nested(value, c.Config.Template)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(structs))
+	for i, str := range structs {
+		if texts[i], err = c.nestedClient(i).TemplateToText(str, tpl, data...); err != nil {
+			return nil, fmt.Errorf("rendering %s: %w", str.Name, err)
+		}
+	}
+	return texts, nil
+}
+
+// nestedClient returns the client for the i'th struct from Nested: c for the top level struct, and for
+// its children a copy without TemplatorConfig.Table, since they're named by their own field or TableNameTags
+func (c *Client) nestedClient(i int) *Client {
+	if i == 0 || c.Config.Template.Table == "" {
+		return c
+	}
+	cc := *c
+	cc.Config.Template.Table = ""
+	cc.Templator.Config.Table = ""
+	return &cc
+}
+
+// Nested splits value into a Struct per table using Struct.Extract, recursing into children's ChildTag
+// fields. Each child row's ParentPrimaryKeyTag fields are set from its parent row's primary key, in order.
+// Parents always come before their children. cfg.Table only applies to the top level struct.
+func Nested(value any, cfg client.TemplatorConfig) ([]meta.Struct, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	ConfigureStruct(&str, cfg)
+
+	children := str.Fields().WithTagTrue(cfg.ChildTag)
+	if len(children) == 0 {
+		return []meta.Struct{str}, nil
+	}
+
+	primarykey := ColumnFields(str, cfg).WithTagTrue(cfg.PrimaryKeyTag)
+	data := make(meta.Data, len(str.Data))
+	for i, row := range str.Data {
+		if data[i], err = setParentKeys(row, primarykey, children, cfg); err != nil {
+			return nil, err
+		}
+	}
+	str.Data = data
+
+	extracted := str.Extract(children.Names())
+	structs := []meta.Struct{str}
+	childCfg := cfg
+	childCfg.Table = ""
+	for _, child := range children {
+		nested, err := Nested(extracted[child.Name], childCfg)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, nested...)
+	}
+	return structs, nil
+}
+
+// setParentKeys returns a copy of row whose children have their parent key fields set from row's primary key
+func setParentKeys(row any, primarykey, children meta.Fields, cfg client.TemplatorConfig) (any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	parent := reflect.New(rv.Type()).Elem()
+	parent.Set(rv)
+
+	for _, child := range children {
+		childStr, err := child.ToStruct()
+		if err != nil {
+			return nil, err
+		}
+		parentkey := childStr.Fields().WithTagTrue(cfg.ParentPrimaryKeyTag)
+		if len(parentkey) == 0 {
+			continue
+		}
+		if len(parentkey) != len(primarykey) {
+			return nil, fmt.Errorf("%s has %d %s fields, but its parent has %d %s fields",
+				child.Name, len(parentkey), cfg.ParentPrimaryKeyTag, len(primarykey), cfg.PrimaryKeyTag)
+		}
+
+		set := func(elem reflect.Value) error {
+			for i, field := range parentkey {
+				src := parent.FieldByIndex(primarykey[i].StructField.Index)
+				dst := elem.FieldByIndex(field.StructField.Index)
+				if !src.Type().ConvertibleTo(dst.Type()) {
+					return fmt.Errorf("cannot set %s.%s (%s) from %s (%s)", child.Name, field.Name, dst.Type(), primarykey[i].Name, src.Type())
+				}
+				dst.Set(src.Convert(dst.Type()))
+			}
+			return nil
+		}
+
+		cv := parent.FieldByIndex(child.StructField.Index)
+		switch cv.Kind() {
+		case reflect.Struct:
+			if err := set(cv); err != nil {
+				return nil, err
+			}
+		case reflect.Slice, reflect.Array:
+			if cv.Kind() == reflect.Slice {
+				// copy, so the caller's slice isn't modified
+				cp := reflect.MakeSlice(cv.Type(), cv.Len(), cv.Len())
+				reflect.Copy(cp, cv)
+				cv.Set(cp)
+			}
+			for j := 0; j < cv.Len(); j++ {
+				elem := cv.Index(j)
+				if elem.Kind() == reflect.Pointer {
+					if elem.IsNil() {
+						continue
+					}
+					cp := reflect.New(elem.Type().Elem())
+					cp.Elem().Set(elem.Elem())
+					elem.Set(cp)
+					elem = cp.Elem()
+				}
+				if err := set(elem); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s must be a struct or slice of structs, got %s", child.Name, cv.Type())
+		}
+	}
+	return parent.Interface(), nil
+}
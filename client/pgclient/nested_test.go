@@ -0,0 +1,108 @@
+package pgclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type nestedOrder struct {
+	ID    int64         `db:"id" primarykey:"true"`
+	Lines []nestedLine  `struct:"true" table:"order_lines"`
+	Notes []*nestedNote `struct:"true"`
+}
+
+type nestedLine struct {
+	OrderID int64  `db:"order_id" parentprimarykey:"true" primarykey:"true"`
+	LineNo  int    `db:"line_no" primarykey:"true"`
+	Item    string `db:"item"`
+}
+
+type nestedNote struct {
+	OrderID int64  `db:"order_id" parentprimarykey:"true"`
+	Text    string `db:"text"`
+}
+
+func TestNested(t *testing.T) {
+	orders := []nestedOrder{
+		{ID: 1, Lines: []nestedLine{{LineNo: 1, Item: "a"}, {LineNo: 2, Item: "b"}}, Notes: []*nestedNote{{Text: "x"}}},
+		{ID: 2, Lines: []nestedLine{{LineNo: 1, Item: "c"}}},
+	}
+	structs, err := pgclient.Nested(orders, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(structs) != 3 {
+		t.Fatalf("expected 3 structs, got %d", len(structs))
+	}
+
+	lines := structs[1]
+	if len(lines.Data) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines.Data))
+	}
+	for i, expect := range []int64{1, 1, 2} {
+		if got := lines.Data[i].(nestedLine).OrderID; got != expect {
+			t.Errorf("line %d: got order_id %d, expected %d", i, got, expect)
+		}
+	}
+	if got := structs[2].Data[0].(*nestedNote).OrderID; got != 1 {
+		t.Errorf("note: got order_id %d, expected 1", got)
+	}
+	if orders[0].Lines[0].OrderID != 0 || orders[0].Notes[0].OrderID != 0 {
+		t.Errorf("caller's rows were modified: %+v", orders[0])
+	}
+
+	parent, err := pgclient.TemplateToText(structs[0], pgclient.PGTemplates.CreateTable, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(parent, "lines") || strings.Contains(parent, "notes") {
+		t.Errorf("unexpected child columns in:\n%s", parent)
+	}
+	child, err := pgclient.TemplateToText(lines, pgclient.PGTemplates.CreateTable, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`"` + pgclient.TemplateConfig.Schema + `"."order_lines"`,
+		`FOREIGN KEY ( "order_id" ) REFERENCES "` + pgclient.TemplateConfig.Schema + `"."nestedorder" ( "id" )`,
+	} {
+		if !strings.Contains(child, expect) {
+			t.Errorf("expected %q in:\n%s", expect, child)
+		}
+	}
+}
+
+func TestNestedTable(t *testing.T) {
+	c := pgclient.NewClient()
+	c.Config.Template.Schema = "sales"
+	c.Config.Template.Table = "orders"
+	orders := []nestedOrder{{ID: 1, Lines: []nestedLine{{LineNo: 1, Item: "a"}}, Notes: []*nestedNote{{Text: "x"}}}}
+
+	var testCases = []struct {
+		Name   string
+		Tpl    string
+		Expect []string
+	}{
+		{Name: "CreateTable", Tpl: c.Templator.CreateTable, Expect: []string{`"sales"."orders"`, `"sales"."order_lines"`, `"sales"."notes"`}},
+		{Name: "PutTempToTable", Tpl: c.Templator.PutTempToTableStrategy(meta.ReplaceChanges), Expect: []string{`into "sales"."orders"`, `into "sales"."order_lines"`, `into "sales"."notes"`}},
+	}
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			texts, err := c.NestedText(orders, v.Tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(texts) != len(v.Expect) {
+				t.Fatalf("expected %d texts, got %d", len(v.Expect), len(texts))
+			}
+			for i, expect := range v.Expect {
+				if !strings.Contains(texts[i], expect) {
+					t.Errorf("expected %q in:\n%s", expect, texts[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package pgclient
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AfterConnect prepares a new connection: it registers the Nullable codecs (see RegisterNullable)
+// and any composite or enum types (see RegisterTypes). Connect and ConnectPool use it.
+func AfterConnect(types ...string) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		RegisterNullable(conn.TypeMap())
+		return RegisterTypes(ctx, conn, types...)
+	}
+}
+
+// RegisterNullable lets m encode null.Nullable[T] values as NULL or their V, and scan NULL or
+// a value back into them. Nullable implements sql.Scanner, which pgx prefers over these plans,
+// so scan through NullableTarget to use them; Get and Rows already do.
+func RegisterNullable(m *pgtype.Map) {
+	m.TryWrapEncodePlanFuncs = append([]pgtype.TryWrapEncodePlanFunc{TryWrapNullableEncodePlan}, m.TryWrapEncodePlanFuncs...)
+	m.TryWrapScanPlanFuncs = append([]pgtype.TryWrapScanPlanFunc{TryWrapNullableScanPlan}, m.TryWrapScanPlanFuncs...)
+}
+
+// NullableTarget converts a *null.Nullable[T] to a pointer to the same memory typed as
+// *struct{ V T; Valid bool }, which has no Scan method. Other targets are returned as is.
+func NullableTarget(target any) any {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return target
+	}
+	if _, ok := meta.NullableType(rv.Type().Elem()); !ok {
+		return target
+	}
+	return rv.Convert(reflect.PointerTo(unnamedNullable(rv.Type().Elem()))).Interface()
+}
+
+// unnamedNullable returns struct{ V T; Valid bool } for a Nullable type
+func unnamedNullable(t reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "V", Type: t.Field(0).Type},
+		{Name: "Valid", Type: t.Field(1).Type},
+	})
+}
+
+// TryWrapNullableEncodePlan is a pgtype.TryWrapEncodePlanFunc for null.Nullable values
+func TryWrapNullableEncodePlan(value any) (pgtype.WrappedEncodePlanNextSetter, any, bool) {
+	t, ok := meta.NullableType(reflect.TypeOf(value))
+	if !ok {
+		return nil, nil, false
+	}
+	return &nullableEncodePlan{}, reflect.New(t).Elem().Interface(), true
+}
+
+type nullableEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *nullableEncodePlan) SetNext(next pgtype.EncodePlan) { plan.next = next }
+
+func (plan *nullableEncodePlan) Encode(value any, buf []byte) ([]byte, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.Field(1).Bool() {
+		return nil, nil
+	}
+	return plan.next.Encode(rv.Field(0).Interface(), buf)
+}
+
+// TryWrapNullableScanPlan is a pgtype.TryWrapScanPlanFunc for pointers to null.Nullable values,
+// or the NullableTarget equivalent
+func TryWrapNullableScanPlan(target any) (pgtype.WrappedScanPlanNextSetter, any, bool) {
+	rt := reflect.TypeOf(target)
+	if rt == nil || rt.Kind() != reflect.Pointer {
+		return nil, nil, false
+	}
+	t, ok := meta.NullableType(rt.Elem())
+	if !ok {
+		return nil, nil, false
+	}
+	return &nullableScanPlan{}, reflect.New(t).Interface(), true
+}
+
+type nullableScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (plan *nullableScanPlan) SetNext(next pgtype.ScanPlan) { plan.next = next }
+
+func (plan *nullableScanPlan) Scan(src []byte, target any) error {
+	rv := reflect.ValueOf(target).Elem()
+	if src == nil {
+		rv.SetZero()
+		return nil
+	}
+	v := reflect.New(rv.Field(0).Type())
+	if err := plan.next.Scan(src, v.Interface()); err != nil {
+		return err
+	}
+	rv.Field(0).Set(v.Elem())
+	rv.Field(1).SetBool(true)
+	return nil
+}
+
+// nullableValue returns nil for an invalid null.Nullable and V for a valid one.
+// Other values are returned as is.
+func nullableValue(value any) any {
+	if _, ok := meta.NullableType(reflect.TypeOf(value)); !ok {
+		return value
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.Field(1).Bool() {
+		return nil
+	}
+	return rv.Field(0).Interface()
+}
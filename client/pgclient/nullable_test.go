@@ -0,0 +1,125 @@
+package pgclient_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Nullable mirrors null.Nullable, which is a separate module
+type Nullable[T any] struct {
+	V     T
+	Valid bool
+}
+
+func (n *Nullable[T]) Scan(src any) error {
+	return errors.New("sql.Scanner should be bypassed by NullableTarget")
+}
+
+type nullableTest struct {
+	ID      int64               `db:"id" primarykey:"true"`
+	Count   Nullable[int64]     `db:"count"`
+	Names   Nullable[[]string]  `db:"names"`
+	Created Nullable[time.Time] `db:"created"`
+}
+
+func TestNullableTypes(t *testing.T) {
+	text, err := pgclient.DefaultCreateTableText(nullableTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`"count"` + "\tbigint,",
+		`"names"` + "\ttext[],",
+		`"created"` + "\ttimestamp with time zone,",
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+	for _, v := range []struct {
+		Value  any
+		Expect string
+	}{
+		{Value: Nullable[time.Time]{}, Expect: "timestamp with time zone"},
+		{Value: &Nullable[float64]{}, Expect: "double precision"},
+		{Value: Nullable[nullableTest]{}, Expect: "jsonb"},
+	} {
+		if got := pgclient.PGType(reflect.TypeOf(v.Value)); got != v.Expect {
+			t.Errorf("PGType(%T): got %s, expected %s", v.Value, got, v.Expect)
+		}
+	}
+
+	args, err := pgclient.NamedArgs(nullableTest{Count: Nullable[int64]{V: 3, Valid: true}}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["count"] != int64(3) || args["names"] != nil || args["created"] != nil {
+		t.Errorf("unexpected args: %#v", args)
+	}
+}
+
+func TestNullableCodec(t *testing.T) {
+	m := pgtype.NewMap()
+	pgclient.RegisterNullable(m)
+
+	var testCases = []struct {
+		Name  string
+		OID   uint32
+		Value any
+		Scan  func() (target any, get func() any)
+	}{
+		{
+			Name:  "valid int8",
+			OID:   pgtype.Int8OID,
+			Value: Nullable[int64]{V: 42, Valid: true},
+			Scan: func() (any, func() any) {
+				var n Nullable[int64]
+				return &n, func() any { return n }
+			},
+		},
+		{
+			Name:  "null int8",
+			OID:   pgtype.Int8OID,
+			Value: Nullable[int64]{V: 42},
+			Scan: func() (any, func() any) {
+				n := Nullable[int64]{V: 1, Valid: true}
+				return &n, func() any { return n }
+			},
+		},
+		{
+			Name:  "valid text array",
+			OID:   pgtype.TextArrayOID,
+			Value: Nullable[[]string]{V: []string{"a", "b"}, Valid: true},
+			Scan: func() (any, func() any) {
+				var n Nullable[[]string]
+				return &n, func() any { return n }
+			},
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			buf, err := m.Encode(v.OID, pgtype.BinaryFormatCode, v.Value, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target, get := v.Scan()
+			if err := m.Scan(v.OID, pgtype.BinaryFormatCode, buf, pgclient.NullableTarget(target)); err != nil {
+				t.Fatal(err)
+			}
+			expect := v.Value
+			if !reflect.ValueOf(v.Value).Field(1).Bool() {
+				expect = reflect.Zero(reflect.TypeOf(v.Value)).Interface()
+			}
+			if got := get(); !reflect.DeepEqual(got, expect) {
+				t.Errorf("got %#v, expected %#v", got, expect)
+			}
+		})
+	}
+}
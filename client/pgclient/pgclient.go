@@ -2,17 +2,25 @@ package pgclient
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/exiledavatar/gotoolkit/client"
 	"github.com/exiledavatar/gotoolkit/meta"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Client struct {
 	client.Client[pgx.Conn]
+	Pool *pgxpool.Pool // set by ConnectPool, used instead of Conn when present
+	Tx   pgx.Tx        // set by WithTx, used instead of Pool or Conn when present
+
+	Types []string // composite and enum types registered on every connection, see RegisterTypes
 }
 
 func NewConfig(cfg ...client.Config) client.Config {
@@ -33,21 +41,31 @@ func DefaultConfig() client.Config {
 
 func NewClient(cfg ...client.Config) Client {
 
-	return Client{client.Client[pgx.Conn]{
+	return Client{Client: client.Client[pgx.Conn]{
 		Config:    NewConfig(cfg...),
 		Templator: PGTemplates,
 	}}
 
 }
 
+// Connect connects using ConnectionString, or a connection string built from the
+// ConnectionConfig components if it is empty. See ConnConfig.
 func (c *Client) Connect(ctx context.Context) error {
-	var err error
-	c.Conn, err = pgx.Connect(ctx, c.Config.Connection.ConnectionString)
+	cfg, err := ConnConfig(c.Config.Connection)
 	if err != nil {
 		return err
 	}
-	c.Config.Template.Schema = c.Config.Connection.Schema
-	c.Templator.Config.Schema = c.Config.Connection.Schema
+	c.Conn, err = pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := AfterConnect(c.Types...)(ctx, c.Conn); err != nil {
+		return err
+	}
+	if c.Config.Connection.Schema != "" {
+		c.Config.Template.Schema = c.Config.Connection.Schema
+		c.Templator.Config.Schema = c.Config.Connection.Schema
+	}
 	return nil
 }
 
@@ -63,24 +81,70 @@ func (c *Client) Connect(ctx context.Context) error {
 // }
 
 var TemplateConfig = client.TemplatorConfig{
-	Schema:           "public",
-	Table:            "",
-	TableNameTags:    []string{"table"},
-	FieldNameTags:    []string{"pg", "postgres", "db", "sql"},
-	LastInsertTags:   []string{"pgli"},
-	TaggedFieldsOnly: false, // include all fields by default
-	DataTypeTag:      "pgtype",
-	PrimaryKeyTag:    "primarykey",
+	Schema:              "public",
+	Table:               "",
+	TableNameTags:       []string{"table"},
+	FieldNameTags:       []string{"pg", "postgres", "db", "sql"},
+	LastInsertTags:      []string{"pgli"},
+	TaggedFieldsOnly:    false, // include all fields by default
+	DataTypeTag:         "pgtype",
+	PrimaryKeyTag:       "primarykey",
+	PreviousNameTag:     "was",
+	ChildTag:            "struct",
+	ParentPrimaryKeyTag: "parentprimarykey",
+	IndexTag:            "index",
+	UniqueTag:           "unique",
+	IndexWhereTag:       "indexwhere",
+	NotNullTag:          "notnull",
+	DefaultTag:          "default",
+	CheckTag:            "check",
+	GeneratedTag:        "generated",
+	CompositeTag:        "composite",
+	ReturningTag:        "returning",
+	IdentityTag:         "identity",
+	CreatedAtTag:        "createdat",
+	UpdatedAtTag:        "updatedat",
+	VersionTag:          "version",
+	RowHashTag:          "rowhash",
+	ValidFromTag:        "validfrom",
+	ValidToTag:          "validto",
+	BatchIDTag:          "batchid",
+	CheckpointTable:     "load_checkpoints",
 }
 
 var FuncMap = template.FuncMap{
-	"pgtype":  GoToPGType,
-	"pgtypes": GoToPGTypes,
+	"pgtype":       GoToPGType,
+	"pgtypes":      GoToPGTypes,
+	"indexes":      Indexes,
+	"fieldpgtypes": FieldPGTypes,
+	"columndefs":   ColumnDefinitions,
+
+	// managed columns, see ManagedFields
+	"copynullfields": CopyNullFields,
+	"updatefields":   UpdateFields,
+	"updateset":      UpdateSet,
+	"tempselect":     TempSelect,
+
+	// quoting and validation, every statement in PGTemplates goes through these
+	"quoteident":   QuoteIdentifier,
+	"quoteidents":  QuoteIdentifiers,
+	"quoteliteral": QuoteLiteral,
+	"tableident":   TableIdentifier,
+	"tempident":    TempTableIdentifier,
+	"params":       NamedParameters,
+	"valueparams":  ValueParameters,
+	"expression":   SafeExpression,
+	"fail":         templateError,
 }
 
 var TemplateData = map[string]any{
-	"Config":  TemplateConfig,
-	"TypeMap": TypeMap,
+	"Config":    TemplateConfig,
+	"TypeMap":   TypeMap,
+	"defaults":  []string(nil), // zero valued DefaultFields, set per row by Put
+	"returning": []string(nil), // columns for a returning clause, set by Put
+	"validfrom": "now()",       // when history versions take effect, set by Load
+	// set by Load, PutTempToTableReplaceChanges and ReplaceAll return the inserted and written counts
+	"countinserts": false,
 }
 
 // type Templator struct {
@@ -104,77 +168,326 @@ var PGTemplates = client.Templator{
 	Config:       TemplateConfig,
 	FuncMap:      FuncMap,
 	Data:         TemplateData,
-	CreateSchema: `create schema if not exists {{ .Config.Schema | tolower }}`,
-	DropSchema:   `drop schema if exists {{ .Config.Schema | tolower }}`,
+	CreateSchema: `create schema if not exists {{ .Config.Schema | tolower | quoteident }}`,
+	DropSchema:   `drop schema if exists {{ .Config.Schema | tolower | quoteident }}`,
 	CreateTable: `{{- "\n" -}}
-	CREATE TABLE IF NOT EXISTS {{ .Struct.TagIdentifier .Config.TableNameTags | tolower }} (
-		{{- $fields := .Struct.Fields -}}
-		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = .Struct.Fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
-		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices -}}
-		{{- $tagtypes := $fields.NonEmptyTagValues .Config.DataTypeTag -}}
-		{{- $defaulttypes := pgtypes $fields.TypeNames -}}
-		{{- $types := coalesce $tagtypes $defaulttypes "text" -}}
-		{{- $columnDefs := joinslices "\t" ",\n\t" $names $types -}}
+	CREATE TABLE IF NOT EXISTS {{ tableident .Struct .Config }} (
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $types := fieldpgtypes $fields .Config -}}
+		{{- $definitions := columndefs $types $fields .Config -}}
+		{{- $columnDefs := joinslices "\t" ",\n\t" $names $definitions -}}
 		{{- print "\n\t" $columnDefs -}}
-		{{- $primarykeyfields := $fields.WithTagTrue .Config.PrimaryKeyTag -}}
-		{{- $primarykey := $primarykeyfields.TagNames .Config.FieldNameTags | join ", " -}}
+		{{- $primarykeyfields := $fields.WithTagTrue .Config.PrimaryKeyTag .Config.ValidFromTag -}}
+		{{- $primarykey := $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents | join ", " -}}
 		{{- if ne $primarykey "" -}}{{- printf ",\n\tPRIMARY KEY ( %s )" $primarykey -}}{{- end -}}
+		{{- $parentkeyfields := $fields.WithTagTrue .Config.ParentPrimaryKeyTag -}}
+		{{- if and .Struct.Parent $parentkeyfields -}}
+		{{- $parentkey := $parentkeyfields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents | join ", " -}}
+		{{- $parentprimarykeyfields := .Struct.Parent.Fields.WithTagTrue .Config.PrimaryKeyTag -}}
+		{{- $parentprimarykey := $parentprimarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents | join ", " -}}
+		{{- $parenttable := tableident .Struct.Parent .Config -}}
+		{{- printf ",\n\tFOREIGN KEY ( %s ) REFERENCES %s ( %s )" $parentkey $parenttable $parentprimarykey -}}
+		{{- end -}}
 		{{- "\n)" -}}
 		
+		`,
+	CreateIndexes: `
+		{{- $table := tableident .Struct .Config -}}
+		{{- range indexes .Struct .Config }}
+		create {{ if .Unique }}unique {{ end }}index if not exists {{ quoteident .Name }} on {{ $table }}
+		{{- if .Method }} using {{ .Method }}{{ end }} ( {{ .Columns | quoteidents | join ", " }} )
+		{{- if .Where }} where {{ expression .Where }}{{ end }};
+		{{- end }}
 		`,
 	CreateTempTable: `
-		create temp table _tmp_{{ .Struct.TagName .Config.TableNameTags | tolower }} (
-		like {{ .Struct.TagIdentifier .Config.TableNameTags | tolower }}
+		create temp table {{ tempident .Struct .Config }} (
+		like {{ tableident .Struct .Config }}
 		excluding constraints ) 
+		{{- $nullable := ( copynullfields .Struct .Config ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}};
+		alter table {{ tempident .Struct .Config }}
+			add column "_staged" bigint generated always as identity
+		{{- range $nullable }},
+			alter column {{ . }} drop not null
+		{{- end }}
 		`,
-	DropTable: `drop table if exists {{ .Struct.TagIdentifier .Config.TableNameTags | tolower }}`,
+	DropTable:     `drop table if exists {{ tableident .Struct .Config }}`,
+	DropTempTable: `drop table if exists {{ tempident .Struct .Config }}`,
 	Put: `{{- "\n" -}}
-		insert into {{ .Struct.TagIdentifier .Config.TableNameTags | tolower }} ( 
-			{{- $fields := .Struct.Fields -}}
-			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = .Struct.Fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
-			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices -}}
+		insert into {{ tableident .Struct .Config }} ( 
+			{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+			{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+			{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+			{{- $params := $fields.TagNames .Config.FieldNameTags | tolowerslices | valueparams .defaults -}}
+			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
 			{{- "\n\t" -}}{{- $names | join ",\n\t" }}{{- "\n" -}}
 			) values (
-				{{- "\n\t" -}}:{{- $names | join ",\n\t:" -}}
-				{{- "\n) on conflict (" -}}
-				{{- $primarykeyfields := $fields.WithTagTrue .Config.PrimaryKeyTag -}}
-				{{- $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | join ", " -}}
-				) do nothing
+				{{- "\n\t" -}}{{- $params | join ",\n\t" -}}
+				{{- "\n)" -}}
+				{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+				{{- if $primarykey }} on conflict ({{ $primarykey | join ", " }}) do nothing{{ end }}
+				{{- if .returning }}
+				returning {{ .returning | quoteidents | join ", " }}
+				{{- end }}
 				`,
 	PutTempToTable: `{{- "\n" -}}
-		{{- $primarykeyfields := .Struct.Fields.WithTagTrue .Config.PrimaryKeyTag -}}
-		{{- $primarykey := $primarykeyfields.TagNames .Config.FieldNameTags | join ", " -}}
-
-		insert into {{ .Struct.TagIdentifier .Config.TableNameTags | tolower }} (
-		select distinct 
-		{{- if $primarykeyfields }}
-		{{- $pkey := $primarykeyfields.TagNames .Config.FieldNameTags | join ", tmp." -}}
-		{{ "" }} on ( tmp.{{ $pkey }} ) 
-		{{- end }}		
-		tmp.*
-		from _tmp_{{ .Struct.TagName .Config.TableNameTags | tolower }} tmp 	
-		) {{ if $primarykey }} on conflict ( {{ $primarykey }} ) do nothing{{ end }}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		insert into {{ tableident .Struct .Config }} ( {{ $names | join ", " }} )
+		select {{ if $primarykey }}distinct on ( tmp.{{ $primarykey | join ", tmp." }} ) {{ end }}tmp.{{ $names | join ", tmp." }}
+		from ( select {{ tempselect .Struct $fields .Config | join ", " }}, "_staged" from {{ tempident .Struct .Config }} ) tmp
+		{{- if $primarykey }}
+		order by tmp.{{ $primarykey | join ", tmp." }}, tmp."_staged" desc
+		on conflict ( {{ $primarykey | join ", " }} ) do nothing
+		{{- end }}
+		`,
+	PutAppendAll: `{{- "\n" -}}
+		insert into {{ tableident .Struct .Config }} ( 
+			{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+			{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+			{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+			{{- $params := $fields.TagNames .Config.FieldNameTags | tolowerslices | valueparams .defaults -}}
+			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+			{{- "\n\t" -}}{{- $names | join ",\n\t" }}{{- "\n" -}}
+			) values (
+				{{- "\n\t" -}}{{- $params | join ",\n\t" -}}
+				{{- "\n)" }}
+				{{- if .returning }}
+				returning {{ .returning | quoteidents | join ", " }}
+				{{- end }}
+				`,
+	PutReplaceChanges: `{{- "\n" -}}
+		insert into {{ tableident .Struct .Config }} as dst ( 
+			{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+			{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+			{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+			{{- $params := $fields.TagNames .Config.FieldNameTags | tolowerslices | valueparams .defaults -}}
+			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+			{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+			{{- $columns := ( updatefields $fields .Config ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+			{{- $versionfields := ( $fields.WithTagTrue .Config.VersionTag ).TagNames .Config.FieldNameTags | tolowerslices -}}
+			{{- $version := $versionfields | quoteidents -}}
+			{{- $versionparams := $versionfields | params -}}
+			{{- "\n\t" -}}{{- $names | join ",\n\t" }}{{- "\n" -}}
+			) values (
+				{{- "\n\t" -}}{{- $params | join ",\n\t" -}}
+				{{- "\n)" -}}
+				{{- if $primarykey }} on conflict ( {{ $primarykey | join ", " }} ) 
+				{{- if $columns }} do update set
+				{{ updateset $fields .Config | join ",\n\t" }}
+				where ( dst.{{ $columns | join ", dst." }} ) is distinct from ( excluded.{{ $columns | join ", excluded." }} )
+				{{- /* the row's own version, excluded has the default when it's zero */ -}}
+				{{- range $i, $v := $version }} and dst.{{ $v }} = {{ index $versionparams $i }}{{ end }}
+				{{- else }} do nothing{{ end }}
+				{{- end }}
+				{{- if .returning }}
+				returning {{ .returning | quoteidents | join ", " }}
+				{{- end }}
+				`,
+	PutTempToTableAppendAll: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		insert into {{ tableident .Struct .Config }} ( {{ $names | join ", " }} )
+		select {{ $names | join ", " }}
+		from ( select {{ tempselect .Struct $fields .Config | join ", " }} from {{ tempident .Struct .Config }} ) tmp
+		`,
+	PutTempToTableReplaceChanges: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $columns := ( updatefields $fields .Config ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $version := ( $fields.WithTagTrue .Config.VersionTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{ if .countinserts }}with written as (
+		{{ end -}}
+		insert into {{ tableident .Struct .Config }} as dst ( {{ $names | join ", " }} )
+		select {{ if $primarykey }}distinct on ( tmp.{{ $primarykey | join ", tmp." }} ) {{ end }}tmp.{{ $names | join ", tmp." }}
+		from ( select {{ tempselect .Struct $fields .Config | join ", " }}, "_staged" from {{ tempident .Struct .Config }} ) tmp
+		{{- if $primarykey }}
+		order by tmp.{{ $primarykey | join ", tmp." }}, tmp."_staged" desc
+		on conflict ( {{ $primarykey | join ", " }} )
+		{{- if $columns }} do update set
+		{{ updateset $fields .Config | join ",\n\t" }}
+		where ( dst.{{ $columns | join ", dst." }} ) is distinct from ( excluded.{{ $columns | join ", excluded." }} )
+		{{- range $version }} and dst.{{ . }} = excluded.{{ . }}{{ end }}
+		{{- else }} do nothing{{ end }}
+		{{- end }}
+		{{- if .countinserts }}
+		returning ( xmax = 0 ) as inserted
+		)
+		select count(*) filter ( where inserted ), count(*) from written
+		{{- end }}
+		`,
+	PutTempToTableReplaceAll: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $columns := ( updatefields $fields .Config ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $version := ( $fields.WithTagTrue .Config.VersionTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		delete from {{ tableident .Struct .Config }} dst
+		{{- if $primarykey }}
+		where not exists (
+			select 1
+			from {{ tempident .Struct .Config }} tmp
+			where ( tmp.{{ $primarykey | join ", tmp." }} ) = ( dst.{{ $primarykey | join ", dst." }} )
+		)
+		{{- end }};
+		{{ if .countinserts }}with written as (
+		{{ end -}}
+		insert into {{ tableident .Struct .Config }} as dst ( {{ $names | join ", " }} )
+		select {{ if $primarykey }}distinct on ( tmp.{{ $primarykey | join ", tmp." }} ) {{ end }}tmp.{{ $names | join ", tmp." }}
+		from ( select {{ tempselect .Struct $fields .Config | join ", " }}, "_staged" from {{ tempident .Struct .Config }} ) tmp
+		{{- if $primarykey }}
+		order by tmp.{{ $primarykey | join ", tmp." }}, tmp."_staged" desc
+		on conflict ( {{ $primarykey | join ", " }} )
+		{{- if $columns }} do update set
+		{{ updateset $fields .Config | join ",\n\t" }}
+		{{- range $i, $v := $version }}{{ if $i }} and{{ else }}
+		where{{ end }} dst.{{ $v }} = excluded.{{ $v }}{{ end }}
+		{{- else }} do nothing{{ end }}
+		{{- end }}
+		{{- if .countinserts }}
+		returning ( xmax = 0 ) as inserted
+		)
+		select count(*) filter ( where inserted ), count(*) from written
+		{{- end }}
+		`,
+	PutTempToTableHistory: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $key := ( ( $fields.WithTagTrue .Config.PrimaryKeyTag ).WithoutTagTrue .Config.ValidFromTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $rowhash := index ( ( $fields.WithTagTrue .Config.RowHashTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $validfrom := index ( ( $fields.WithTagTrue .Config.ValidFromTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $validto := index ( ( $fields.WithTagTrue .Config.ValidToTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.ValidFromTag .Config.ValidToTag -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $src := printf "select distinct on ( tmp.%[1]s ) tmp.%[2]s\n\t\tfrom ( select %[3]s, \"_staged\" from %[4]s ) tmp\n\t\torder by tmp.%[1]s, tmp.\"_staged\" desc" ( $key | join ", tmp." ) ( $names | join ", tmp." ) ( tempselect .Struct $fields .Config | join ", " ) ( tempident .Struct .Config ) }}
+		update {{ tableident .Struct .Config }} dst
+		set {{ $validto }} = {{ .validfrom }}
+		from (
+		{{ $src }}
+		) src
+		where ( dst.{{ $key | join ", dst." }} ) = ( src.{{ $key | join ", src." }} )
+		and dst.{{ $validto }} is null
+		and dst.{{ $validfrom }} < {{ .validfrom }}
+		and dst.{{ $rowhash }} is distinct from src.{{ $rowhash }};
+		insert into {{ tableident .Struct .Config }} ( {{ $names | join ", " }}, {{ $validfrom }} )
+		select src.{{ $names | join ", src." }}, {{ .validfrom }}
+		from (
+		{{ $src }}
+		) src
+		where not exists (
+			select 1
+			from {{ tableident .Struct .Config }} cur
+			where ( cur.{{ $key | join ", cur." }} ) = ( src.{{ $key | join ", src." }} )
+			and cur.{{ $validto }} is null
+			and cur.{{ $rowhash }} = src.{{ $rowhash }}
+		)
 		`,
 	Get: `{{- "\n" -}}
 		select
-			{{- $fields := .Struct.Fields -}}
-			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = .Struct.Fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
-			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices -}}
+			{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
 			{{- "\n\t" -}}{{- $names | join ",\n\t" }}{{- "\n" -}}
 		from
-			{{ .Struct.TagIdentifier .Config.TableNameTags | tolower }}
+			{{ tableident .Struct .Config }}
+		{{ if .where -}}where {{ .where }}{{- end }}
+		{{ if .orderby -}}order by {{ .orderby }}{{- end }}
 		{{ if .rowlimit -}}limit {{ .rowlimit }}{{- end }}
-		{{- "\n)" -}}
+		{{ if .rowoffset -}}offset {{ .rowoffset }}{{- end }}
 		`,
-	GetMostRecent: `{{- "\n" -}}
+	GetPage: `{{- "\n" -}}
 		select
-			{{- $fields := .Struct.Fields.WithTagTrue .Config.LastInsertTags -}}
-			{{- names := $fields.NonEmptyTagValues .Config.LastInsertTags -}}
+			{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+			{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+			{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
 			{{- "\n\t" -}}{{- $names | join ",\n\t" }}{{- "\n" -}}
 		from
-			{{ .Struct.TagIdentifier .Config.TableNameTags | tolower }}
-		{{- "\n)" -}}
-	`,
+			{{ tableident .Struct .Config }}
+		{{- $primarykeyfields := $fields.WithTagTrue .Config.PrimaryKeyTag .Config.ValidFromTag -}}
+		{{- $params := $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | params -}}
+		{{- $primarykey := $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents }}
+		{{ if or .after .where -}}where {{ end -}}
+		{{- if .after -}}( {{ $primarykey | join ", " }} ) > ( {{ $params | join ", " }} ){{- end -}}
+		{{- if and .after .where }} and {{ end -}}
+		{{- if .where -}}( {{ .where }} ){{- end }}
+		order by {{ $primarykey | join ", " }}
+		limit {{ .pagesize }}
+		`,
+	GetVersion: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $version := ( $fields.WithTagTrue .Config.VersionTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $primarykeyfields := $fields.WithTagTrue .Config.PrimaryKeyTag -}}
+		{{- $params := $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | params -}}
+		{{- $primarykey := $primarykeyfields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		select {{ index $version 0 }}
+		from {{ tableident .Struct .Config }}
+		where ( {{ $primarykey | join ", " }} ) = ( {{ $params | join ", " }} )
+		`,
+	VersionConflicts: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithoutTagTrue .Config.GeneratedTag -}}
+		{{- $fields = $fields.WithoutTagValue .Config.IdentityTag "always" -}}
+		{{- $version := index ( ( $fields.WithTagTrue .Config.VersionTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $primarykey := ( $fields.WithTagTrue .Config.PrimaryKeyTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		select tmp.{{ $primarykey | join ", tmp." }}, tmp.{{ $version }}, dst.{{ $version }}
+		from {{ tempident .Struct .Config }} tmp
+		join {{ tableident .Struct .Config }} dst on ( dst.{{ $primarykey | join ", dst." }} ) = ( tmp.{{ $primarykey | join ", tmp." }} )
+		where dst.{{ $version }} is distinct from tmp.{{ $version }}
+		`,
+	HistoryConflicts: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $key := ( ( $fields.WithTagTrue .Config.PrimaryKeyTag ).WithoutTagTrue .Config.ValidFromTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- $rowhash := index ( ( $fields.WithTagTrue .Config.RowHashTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $validfrom := index ( ( $fields.WithTagTrue .Config.ValidFromTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		{{- $validto := index ( ( $fields.WithTagTrue .Config.ValidToTag ).TagNames .Config.FieldNameTags | tolowerslices | quoteidents ) 0 -}}
+		select tmp.{{ $key | join ", tmp." }}, dst.{{ $validfrom }}
+		from (
+			select distinct on ( {{ $key | join ", " }} ) {{ $key | join ", " }}, {{ $rowhash }}
+			from {{ tempident .Struct .Config }}
+			order by {{ $key | join ", " }}, "_staged" desc
+		) tmp
+		join {{ tableident .Struct .Config }} dst on ( dst.{{ $key | join ", dst." }} ) = ( tmp.{{ $key | join ", tmp." }} )
+		where dst.{{ $validto }} is null
+		and dst.{{ $validfrom }} >= {{ .validfrom }}
+		and dst.{{ $rowhash }} is distinct from tmp.{{ $rowhash }}
+		`,
+	GetMostRecent: `{{- "\n" -}}
+		{{- $fields := .Struct.Fields.WithoutTagTrue .Config.ChildTag -}}
+		{{- if .Config.TaggedFieldsOnly -}}{{- $fields = $fields.WithTagTrue .Config.FieldNameTags -}}{{- end -}}
+		{{- $fields = $fields.WithTagTrue .Config.LastInsertTags -}}
+		{{- $names := $fields.TagNames .Config.FieldNameTags | tolowerslices | quoteidents -}}
+		{{- if not $names }}{{ fail "%s has no watermark, it needs a field tagged with one of %v" .Struct.Name .Config.LastInsertTags }}{{ end -}}
+		select
+		{{- range $i, $name := $names }}{{ if $i }},{{ end }}
+			max( {{ $name }} ) as {{ $name }}
+		{{- end }}
+		from
+			{{ tableident .Struct .Config }}
+		having count(*) > 0
+		`,
 }
 
 var XTemp string = `{{- "\n" -}}
@@ -208,6 +521,22 @@ var TypeMap = meta.TypeMap{
 		"smallint": reflect.TypeOf(int(1)),
 		"int":      reflect.TypeOf(int(1)),
 		"bigint":   reflect.TypeOf(int(1)),
+
+		// names as information_schema and NormalizeType report them
+		"character varying":           reflect.TypeOf("string"),
+		"character":                   reflect.TypeOf("string"),
+		"uuid":                        reflect.TypeOf("string"),
+		"integer":                     reflect.TypeOf(int32(1)),
+		"boolean":                     reflect.TypeOf(true),
+		"real":                        reflect.TypeOf(float32(1.0)),
+		"double precision":            reflect.TypeOf(float64(1.0)),
+		"numeric":                     reflect.TypeOf(float64(1.0)),
+		"date":                        reflect.TypeOf(time.Time{}),
+		"timestamp with time zone":    reflect.TypeOf(time.Time{}),
+		"timestamp without time zone": reflect.TypeOf(time.Time{}),
+		"bytea":                       reflect.TypeOf([]byte{}),
+		"json":                        reflect.TypeOf(json.RawMessage{}),
+		"jsonb":                       reflect.TypeOf(json.RawMessage{}),
 	},
 	To: meta.To{
 		reflect.TypeOf(string("string")): "text",
@@ -220,8 +549,20 @@ var TypeMap = meta.TypeMap{
 		reflect.TypeOf(float32(1.0)):     "float4",
 		reflect.TypeOf(float64(1.0)):     "float8",
 		reflect.TypeOf(time.Time{}):      "timestamp with time zone",
-		reflect.TypeOf([]byte{}):         "bytea[]",
-		nil:                              "bytea[]", // serves as a default
+		reflect.TypeOf([]byte{}):         "bytea",
+		nil:                              "text", // serves as a default
+
+		reflect.TypeOf(json.RawMessage{}): "jsonb",
+		reflect.TypeOf(map[string]any{}):  "jsonb",
+		reflect.TypeOf([]string{}):        "text[]",
+		reflect.TypeOf([]bool{}):          "boolean[]",
+		reflect.TypeOf([]int{}):           "bigint[]",
+		reflect.TypeOf([]int16{}):         "smallint[]",
+		reflect.TypeOf([]int32{}):         "bigint[]",
+		reflect.TypeOf([]int64{}):         "bigint[]",
+		reflect.TypeOf([]float32{}):       "float4[]",
+		reflect.TypeOf([]float64{}):       "float8[]",
+		reflect.TypeOf([]time.Time{}):     "timestamp with time zone[]",
 	},
 }
 
@@ -234,7 +575,7 @@ var PGToGoTypeMap = map[string]string{
 // GoToPGTypeMap represents the default type mapping
 // we expect to use when sending data to postgres
 var GoToPGTypeMap = map[string]string{
-	"default":          "bytea[]",
+	"default":          "text",
 	"string":           "text",
 	"Date":             "date",
 	"qgenda.Date":      "date",
@@ -254,6 +595,8 @@ var GoToPGTypeMap = map[string]string{
 	"float64": "double precision",
 }
 
+// GoToPGType looks up a type name in GoToPGTypeMap, defaulting to text. Column types are resolved
+// from the field's reflect.Type instead, see PGType.
 func GoToPGType(gotype string) string {
 	pgtype, ok := GoToPGTypeMap[gotype]
 	if !ok {
@@ -284,6 +627,10 @@ func DefaultCreateTableText(value any) (string, error) {
 	return TemplateToText(value, PGTemplates.CreateTable, &TemplateConfig, FuncMap, nil)
 }
 
+func DefaultCreateIndexesText(value any) (string, error) {
+	return TemplateToText(value, PGTemplates.CreateIndexes, &TemplateConfig, FuncMap, nil)
+}
+
 func DefaultCreateTempTableText(value any) (string, error) {
 	return TemplateToText(value, PGTemplates.CreateTempTable, &TemplateConfig, FuncMap, nil)
 }
@@ -292,6 +639,10 @@ func DefaultDropTableText(value any) (string, error) {
 	return TemplateToText(value, PGTemplates.DropTable, &TemplateConfig, FuncMap, nil)
 }
 
+func DefaultDropTempTableText(value any) (string, error) {
+	return TemplateToText(value, PGTemplates.DropTempTable, &TemplateConfig, FuncMap, nil)
+}
+
 func DefaultGetText(value any) (string, error) {
 	return TemplateToText(value, PGTemplates.Get, &TemplateConfig, FuncMap, nil)
 }
@@ -305,52 +656,215 @@ func DefaultPutTempToTableText(value any) (string, error) {
 	return TemplateToText(value, PGTemplates.PutTempToTable, &TemplateConfig, FuncMap, nil)
 }
 
-// TemplateToText is the base function for all XXXToText functions. You can expand Funcmap and TemplateData or use overrides to remove/replace them.
+// TemplateToText is the base function for all XXXToText functions. It renders tpl with the package's FuncMap and
+// TemplateData, overlaid with funcMap and data for this call only. cfg defaults to TemplateConfig. The package
+// globals are only read, so concurrent calls are safe as long as nothing modifies them at the same time.
 func TemplateToText(value any, tpl string, cfg *client.TemplatorConfig, funcMap template.FuncMap, data map[string]any) (string, error) {
 	str, err := meta.ToStruct(value)
 	if err != nil {
 		return "", err
 	}
 
+	tcfg := TemplateConfig
+	if cfg != nil {
+		tcfg = *cfg
+	}
+	ConfigureStruct(&str, tcfg)
+
+	funcs := template.FuncMap{}
+	for k, v := range FuncMap {
+		funcs[k] = v
+	}
 	for k, v := range funcMap {
-		FuncMap[k] = v
+		funcs[k] = v
 	}
 
+	d := map[string]any{}
+	for k, v := range TemplateData {
+		d[k] = v
+	}
 	for k, v := range data {
-		TemplateData[k] = v
+		d[k] = v
 	}
-	tcfg := TemplateConfig
-	if cfg != nil {
-		tcfg = *cfg
+	d["Config"] = tcfg
+	d["TypeMap"] = TypeMap
+
+	return str.ExecuteTemplate(tpl, funcs, d)
+}
+
+// Tabler is implemented by structs that name their own table, eg those written by GenerateStruct.
+// TemplatorConfig.Table and TableNameTags take precedence over it.
+type Tabler interface {
+	TableName() string
+}
+
+// ConfigureStruct updates str with any relevant config items, this is
+// what TemplateToText does before rendering
+func ConfigureStruct(str *meta.Struct, cfg client.TemplatorConfig) {
+	if cfg.Schema != "" {
+		str.NameSpace = []string{cfg.Schema}
 	}
-	TemplateData["Config"] = tcfg
-	// update str with any relevant config items
-	if tcfg.Schema != "" {
-		str.NameSpace = []string{tcfg.Schema}
+	switch {
+	case cfg.Table != "":
+		str.Name = cfg.Table
+	case str.Value.IsValid() && str.Value.CanInterface():
+		if t, ok := str.Value.Interface().(Tabler); ok && t.TableName() != "" {
+			str.Name = t.TableName()
+		}
 	}
-	if tcfg.Table != "" {
-		str.Name = tcfg.Table
+}
+
+// ColumnFields returns the fields the templates treat as columns: all of them except ChildTag
+// fields, or only those tagged with FieldNameTags when TaggedFieldsOnly is set
+func ColumnFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	fields := str.Fields().WithoutTagTrue(cfg.ChildTag)
+	if cfg.TaggedFieldsOnly {
+		return fields.WithTagTrue(cfg.FieldNameTags)
 	}
+	return fields
+}
 
-	TemplateData["TypeMap"] = TypeMap
+// PrimaryKeyFields returns the ColumnFields in the table's primary key, as CreateTable declares it:
+// the PrimaryKeyTag fields, plus the ValidFromTag field of history tables (see HistoryColumnNames)
+func PrimaryKeyFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	return ColumnFields(str, cfg).WithTagTrue(cfg.PrimaryKeyTag, cfg.ValidFromTag)
+}
 
-	return str.ExecuteTemplate(
-		tpl,
-		FuncMap,
-		TemplateData,
-	)
+// ColumnNames returns the lowercased column names for fields, matching the templates
+func ColumnNames(fields meta.Fields, cfg client.TemplatorConfig) []string {
+	names := []string{}
+	for _, name := range fields.TagNames(cfg.FieldNameTags) {
+		names = append(names, strings.ToLower(name))
+	}
+	return names
+}
 
+// WriteFields returns the ColumnFields that are written by Put, PutTempToTable, and COPY,
+// which excludes GeneratedTag fields and GENERATED ALWAYS identity fields (see AlwaysIdentity)
+func WriteFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	fields := ColumnFields(str, cfg).WithoutTagTrue(cfg.GeneratedTag)
+	if cfg.IdentityTag == "" {
+		return fields
+	}
+	return fields.WithoutTagValue(cfg.IdentityTag, "always")
 }
 
-func (c Client) TemplateToText(value any, tpl string) (string, error) {
-	str, err := meta.ToStruct(value)
-	if err != nil {
-		return "", err
+// ColumnDefinitions appends each field's column constraints to its type, for CreateTable. Generated
+// columns only get their expression, and identity columns GENERATED BY DEFAULT AS IDENTITY, or ALWAYS
+// for `identity:"always"`. Otherwise NOT NULL is added for NotNullTag and managed fields, and inferred
+// for fields that can't hold a nil (see Nullable) unless NullableByDefault is set or NotNullTag is false.
+// DefaultTag and CheckTag values are added as is, managed fields default to their ManagedDefault.
+// Types must pass ValidateType and expressions SafeExpression.
+func ColumnDefinitions(types []string, fields meta.Fields, cfg client.TemplatorConfig) ([]string, error) {
+	definitions := []string{}
+	for i, field := range fields {
+		definition := types[i]
+		if err := ValidateType(definition); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		expression := func(tag string) (string, error) {
+			value := field.NonEmptyTagValue(tag)
+			if value == "" {
+				return "", nil
+			}
+			if _, err := SafeExpression(value); err != nil {
+				return "", fmt.Errorf("%s: %w", field.Name, err)
+			}
+			return value, nil
+		}
+
+		generated, err := expression(cfg.GeneratedTag)
+		if err != nil {
+			return nil, err
+		}
+		if generated != "" {
+			definitions = append(definitions, fmt.Sprintf("%s GENERATED ALWAYS AS ( %s ) STORED", definition, generated))
+			continue
+		}
+		if cfg.IdentityTag != "" && field.HasTagTrue(cfg.IdentityTag) {
+			generation := "BY DEFAULT"
+			if AlwaysIdentity(field, cfg) {
+				generation = "ALWAYS"
+			}
+			definitions = append(definitions, fmt.Sprintf("%s GENERATED %s AS IDENTITY", definition, generation))
+			continue
+		}
+
+		if NotNull(field, cfg) {
+			definition += " NOT NULL"
+		}
+		value, err := expression(cfg.DefaultTag)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			value = ManagedDefault(field, cfg)
+		}
+		if value != "" {
+			definition += " DEFAULT " + value
+		}
+		check, err := expression(cfg.CheckTag)
+		if err != nil {
+			return nil, err
+		}
+		if check != "" {
+			definition += fmt.Sprintf(" CHECK ( %s )", check)
+		}
+		definitions = append(definitions, definition)
 	}
+	return definitions, nil
+}
 
-	return str.ExecuteTemplate(
-		tpl,
-		c.Templator.FuncMap,
-		c.Templator.Data,
-	)
+// NotNull returns true when ColumnDefinitions declares field's column NOT NULL: for NotNullTag and managed
+// fields, and for fields that can't hold a nil (see Nullable) unless NullableByDefault is set or NotNullTag
+// is false. Primary key columns aren't declared NOT NULL, the constraint implies it.
+func NotNull(field meta.Field, cfg client.TemplatorConfig) bool {
+	switch {
+	case cfg.NotNullTag != "" && field.HasTagFalse(cfg.NotNullTag):
+		return false
+	case cfg.NotNullTag != "" && field.HasTagTrue(cfg.NotNullTag), ManagedDefault(field, cfg) != "":
+		return true
+	default:
+		return !cfg.NullableByDefault && !field.HasTagTrue(cfg.PrimaryKeyTag) && !Nullable(field.StructField.Type)
+	}
+}
+
+// Nullable returns true for types that can represent NULL: pointers, slices, maps, interfaces,
+// null.Nullable, and sql.Null types
+func Nullable(t reflect.Type) bool {
+	if t == nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		if _, ok := meta.NullableType(t); ok {
+			return true
+		}
+		_, valid := t.FieldByName("Valid")
+		return valid && strings.HasPrefix(t.Name(), "Null")
+	default:
+		return false
+	}
+}
+
+// templateError stops a template from rendering, for input it can't produce valid sql for
+func templateError(format string, args ...any) (string, error) {
+	return "", fmt.Errorf(format, args...)
+}
+
+// TemplateToText renders tpl for value using the client's TemplatorConfig, FuncMap, and Data.
+// Any additional data is merged over the client's Data.
+func (c Client) TemplateToText(value any, tpl string, data ...map[string]any) (string, error) {
+	d := map[string]any{}
+	for k, v := range c.Templator.Data {
+		d[k] = v
+	}
+	for _, dm := range data {
+		for k, v := range dm {
+			d[k] = v
+		}
+	}
+	return TemplateToText(value, tpl, &c.Config.Template, c.Templator.FuncMap, d)
 }
@@ -0,0 +1,139 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB is satisfied by *pgx.Conn, *pgxpool.Pool, and pgx.Tx. Client operations
+// run against it so they behave the same on single connections and pools.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// DB returns the client's transaction if it has one, then its pool, then its connection
+func (c *Client) DB() DB {
+	switch {
+	case c.Tx != nil:
+		return c.Tx
+	case c.Pool != nil:
+		return c.Pool
+	default:
+		return c.Conn
+	}
+}
+
+// ConnectPool connects a pgxpool.Pool instead of a single connection. It is safe for concurrent use.
+// Pool sizing, lifetimes, and health checks are read from ConnectionConfig.Options, see PoolOptions.
+func (c *Client) ConnectPool(ctx context.Context) error {
+	cfg, err := PoolConfig(c.Config.Connection)
+	if err != nil {
+		return err
+	}
+	cfg.AfterConnect = AfterConnect(c.Types...)
+	c.Pool, err = pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if c.Config.Connection.Schema != "" {
+		c.Config.Template.Schema = c.Config.Connection.Schema
+		c.Templator.Config.Schema = c.Config.Connection.Schema
+	}
+	return nil
+}
+
+// Close closes the pool or connection
+func (c *Client) Close(ctx context.Context) error {
+	switch {
+	case c.Pool != nil:
+		c.Pool.Close()
+		return nil
+	case c.Conn != nil:
+		return c.Conn.Close(ctx)
+	default:
+		return nil
+	}
+}
+
+// PoolOptions are the ConnectionConfig.Options keys used to configure a pool. They match the keys
+// pgxpool.ParseConfig accepts and are never sent to the server. Durations use time.ParseDuration.
+var PoolOptions = []string{
+	"pool_max_conns",
+	"pool_min_conns",
+	"pool_max_conn_lifetime",
+	"pool_max_conn_lifetime_jitter",
+	"pool_max_conn_idle_time",
+	"pool_health_check_period",
+}
+
+// PoolConfig expands cc and parses it into a pgxpool.Config, then applies any PoolOptions
+func PoolConfig(cc client.ConnectionConfig) (*pgxpool.Config, error) {
+	expanded, err := ExpandConnectionConfig(cc)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := pgxpool.ParseConfig(ConnectionString(withoutPoolOptions(expanded)))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range expanded.Options {
+		if !strings.HasPrefix(k, "pool_") {
+			continue
+		}
+		var err error
+		switch k {
+		case "pool_max_conns":
+			cfg.MaxConns, err = parseInt32(v)
+		case "pool_min_conns":
+			cfg.MinConns, err = parseInt32(v)
+		case "pool_max_conn_lifetime":
+			cfg.MaxConnLifetime, err = time.ParseDuration(v)
+		case "pool_max_conn_lifetime_jitter":
+			cfg.MaxConnLifetimeJitter, err = time.ParseDuration(v)
+		case "pool_max_conn_idle_time":
+			cfg.MaxConnIdleTime, err = time.ParseDuration(v)
+		case "pool_health_check_period":
+			cfg.HealthCheckPeriod, err = time.ParseDuration(v)
+		default:
+			err = fmt.Errorf("unknown pool option, expected one of %v", PoolOptions)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", k, v, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseInt32(s string) (int32, error) {
+	i, err := strconv.ParseInt(s, 10, 32)
+	return int32(i), err
+}
+
+// withoutPoolOptions returns cc without any pool_ Options, so they aren't sent to the server as runtime params
+func withoutPoolOptions(cc client.ConnectionConfig) client.ConnectionConfig {
+	if cc.Options == nil {
+		return cc
+	}
+	options := map[string]string{}
+	for k, v := range cc.Options {
+		if !strings.HasPrefix(k, "pool_") {
+			options[k] = v
+		}
+	}
+	cc.Options = options
+	return cc
+}
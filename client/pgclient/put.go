@@ -0,0 +1,358 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Result wraps pgconn.CommandTag so it satisfies sql.Result and can be collected in meta.SQLResults
+type Result struct {
+	pgconn.CommandTag
+}
+
+// LastInsertId is not supported by postgres, use RETURNING instead
+func (r Result) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by postgres")
+}
+
+func (r Result) RowsAffected() (int64, error) {
+	return r.CommandTag.RowsAffected(), nil
+}
+
+// PutOptions are per call options for Put and Load
+type PutOptions struct {
+	Strategy  meta.UpdateStrategy // overrides TemplatorConfig.UpdateStrategy when set
+	Returning bool                // Put only, read ReturningFields back into value, see Put
+	ValidFrom time.Time           // meta.History only, when the written versions take effect, defaults to now()
+}
+
+// putOptions returns the first of opts, with the client's defaults filled in
+func (c *Client) putOptions(opts []PutOptions) PutOptions {
+	var o PutOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Strategy == 0 {
+		o.Strategy = c.Config.Template.UpdateStrategy
+	}
+	if o.Strategy == 0 {
+		o.Strategy = meta.AppendChanges
+	}
+	return o
+}
+
+// Put renders the Put template for value and executes it for each element. value may be a single
+// struct or a slice of structs. Named parameters (:name) are bound positionally from each element's
+// NamedArgs and all rows are sent in a single batch. Zero valued DefaultFields are written as DEFAULT.
+// The template is chosen by the update strategy, meta.ReplaceAll needs the whole batch to find missing
+// keys and meta.History a temp table to compare against, so they are delegated to Load.
+//
+// With PutOptions.Returning, the ReturningFields of each row written are read back into value, which
+// must be a pointer or a slice (see addressableElements), and each result is a meta.SQLResult with
+// the returned values. Rows skipped by the strategy, eg on conflict do nothing, are left as is.
+//
+// With meta.ReplaceChanges and a VersionTag field, updates only apply when the row's version matches
+// the table's, and rows that weren't written are checked with GetVersion. Mismatches are returned as
+// ConflictErrors, joined with errors.Join, after the rest of the batch is written. A zero version is a
+// row that was never read, so it's inserted with the default version but conflicts with an existing key.
+func (c *Client) Put(ctx context.Context, value any, opts ...PutOptions) (meta.SQLResults, error) {
+	o := c.putOptions(opts)
+	if o.Strategy == meta.ReplaceAll || o.Strategy == meta.History {
+		if o.Returning {
+			return nil, fmt.Errorf("returning is not supported by the %s strategy", o.Strategy)
+		}
+		return c.Load(ctx, value, o)
+	}
+
+	cfg := c.Config.Template
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	rows := []any(str.Data)
+	var elements []reflect.Value
+	var returning []string
+	var index map[string][]int
+	if o.Returning {
+		if elements, err = addressableElements(value); err != nil {
+			return nil, err
+		}
+		rows = make([]any, len(elements))
+		for i, elem := range elements {
+			rows[i] = elem.Interface()
+		}
+		returning = ColumnNames(ReturningFields(str, cfg), cfg)
+		if index, err = FieldIndexes(value, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// rows with different zero valued defaults need their own statement
+	defaultFields := DefaultFields(str, cfg)
+	queries := map[string]NamedQuery{}
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		defaults := ZeroDefaults(row, defaultFields, cfg)
+		key := strings.Join(defaults, ",")
+		query, ok := queries[key]
+		if !ok {
+			sqlText, err := c.TemplateToText(value, c.Templator.PutStrategy(o.Strategy), map[string]any{
+				"defaults":  defaults,
+				"returning": returning,
+			})
+			if err != nil {
+				return nil, err
+			}
+			query = ParseNamed(sqlText)
+			queries[key] = query
+		}
+
+		args, err := NamedArgs(row, cfg)
+		if err != nil {
+			return nil, err
+		}
+		params, err := query.Bind(args)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(query.SQL, params...)
+	}
+	if batch.Len() == 0 {
+		return nil, nil
+	}
+
+	br := c.DB().SendBatch(ctx, batch)
+	results := meta.SQLResults{}
+	var unchanged []any
+	for i := 0; i < batch.Len(); i++ {
+		if !o.Returning {
+			tag, err := br.Exec()
+			if err != nil {
+				br.Close()
+				return results, err
+			}
+			if tag.RowsAffected() == 0 {
+				unchanged = append(unchanged, rows[i])
+			}
+			results = results.AddResult(Result{tag})
+			continue
+		}
+
+		rows, err := br.Query()
+		if err != nil {
+			br.Close()
+			return results, err
+		}
+		result, err := scanReturning(rows, elements[i], index)
+		if err != nil {
+			br.Close()
+			return results, err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			unchanged = append(unchanged, elements[i].Interface())
+		}
+		results = results.AddResult(result)
+	}
+	if err := br.Close(); err != nil {
+		return results, err
+	}
+
+	if o.Strategy != meta.ReplaceChanges {
+		return results, nil
+	}
+	return results, c.versionConflicts(ctx, value, str, unchanged)
+}
+
+// NamedArgs maps a row's column names, as rendered by the templates (FieldNameTags, lowercased),
+// to its values from meta.ToValueMap. Nil pointers are passed as nil so they are written as NULL,
+// and RowHashTag fields are set to the row's RowHash. ChildTag fields aren't columns, so they're left out.
+func NamedArgs(row any, cfg client.TemplatorConfig) (map[string]any, error) {
+	str, err := meta.ToStruct(row)
+	if err != nil {
+		return nil, err
+	}
+	fields := str.Fields().WithoutTagTrue(cfg.ChildTag)
+	vm := meta.ToValueMap(fields, "")
+
+	types := FieldPGTypes(fields, cfg)
+
+	args := map[string]any{}
+	for i, field := range fields {
+		name := strings.ToLower(field.TagName(cfg.FieldNameTags))
+		value := nullableValue(vm[field.Name])
+		switch {
+		case field.Pointer() && isNilField(field):
+			args[name] = nil
+		case isJSONType(types[i]):
+			if args[name], err = jsonValue(value); err != nil {
+				return nil, fmt.Errorf("encoding %s as json: %w", field.Name, err)
+			}
+		case cfg.RowHashTag != "" && field.HasTagTrue(cfg.RowHashTag):
+			if args[name], err = rowHash(str, cfg); err != nil {
+				return nil, err
+			}
+		default:
+			args[name] = value
+		}
+	}
+	return args, nil
+}
+
+// isNilField checks the parent struct directly, since meta.Value dereferences nil pointers to zero values
+func isNilField(field meta.Field) bool {
+	if field.Parent == nil || !field.Parent.Value.Valid() {
+		return false
+	}
+	rv := field.Parent.Value.Value.FieldByName(field.Name)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// NamedQuery is a query with its :name placeholders replaced by positional $n parameters.
+// Names holds the parameter name for each position.
+type NamedQuery struct {
+	SQL   string
+	Names []string
+}
+
+// ParseNamed replaces :name and :"quoted name" placeholders with $n parameters. Repeated names share a parameter.
+// Quoted strings, quoted identifiers, dollar-quoted strings, comments and :: casts are left untouched.
+func ParseNamed(query string) NamedQuery {
+	var sb strings.Builder
+	nq := NamedQuery{}
+	positions := map[string]int{}
+
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := quotedEnd(query, i, ch)
+			sb.WriteString(query[i:end])
+			i = end
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case ch == '$' && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end == -1 {
+				end = len(query) - i
+			} else {
+				end += 2 * len(tag)
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case ch == ':' && strings.HasPrefix(query[i:], "::"):
+			sb.WriteString("::")
+			i += 2
+		case ch == ':' && i+1 < len(query) && (isNameStart(query[i+1]) || query[i+1] == '"'):
+			j := i + 1
+			var name string
+			if query[j] == '"' {
+				j = quotedEnd(query, j, '"')
+				name = strings.ReplaceAll(strings.TrimSuffix(query[i+2:j], `"`), `""`, `"`)
+			} else {
+				for j < len(query) && isNameChar(query[j]) {
+					j++
+				}
+				name = query[i+1 : j]
+			}
+			position, ok := positions[name]
+			if !ok {
+				nq.Names = append(nq.Names, name)
+				position = len(nq.Names)
+				positions[name] = position
+			}
+			fmt.Fprintf(&sb, "$%d", position)
+			i = j
+		default:
+			sb.WriteByte(ch)
+			i++
+		}
+	}
+	nq.SQL = sb.String()
+	return nq
+}
+
+// Bind returns the positional arguments for the query, in order, from args
+func (q NamedQuery) Bind(args map[string]any) ([]any, error) {
+	params := make([]any, 0, len(q.Names))
+	for _, name := range q.Names {
+		value, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("missing value for named parameter :%s", name)
+		}
+		params = append(params, value)
+	}
+	return params, nil
+}
+
+// BindNamed is a convenience wrapper for ParseNamed and NamedQuery.Bind
+func BindNamed(query string, args map[string]any) (string, []any, error) {
+	nq := ParseNamed(query)
+	params, err := nq.Bind(args)
+	return nq.SQL, params, err
+}
+
+// quotedEnd returns the index just past the quote that closes the one at start,
+// treating doubled quotes as escapes
+func quotedEnd(s string, start int, quote byte) int {
+	for i := start + 1; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string ($$ or $tag$), or the empty string
+func dollarTag(s string) string {
+	if len(s) < 2 || s[0] != '$' {
+		return ""
+	}
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case i == 1 && !isNameStart(s[i]):
+			return ""
+		case !isNameChar(s[i]):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+func isNameChar(ch byte) bool {
+	return isNameStart(ch) || ('0' <= ch && ch <= '9')
+}
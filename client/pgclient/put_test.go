@@ -0,0 +1,114 @@
+package pgclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+func TestParseNamed(t *testing.T) {
+	var testCases = []struct {
+		Name   string
+		Input  string
+		SQL    string
+		Params []string
+	}{
+		{Name: "simple", Input: "insert into t (a, b) values (:a, :b)", SQL: "insert into t (a, b) values ($1, $2)", Params: []string{"a", "b"}},
+		{Name: "repeated", Input: "select :a, :b, :a", SQL: "select $1, $2, $1", Params: []string{"a", "b"}},
+		{Name: "cast", Input: "select :a::text", SQL: "select $1::text", Params: []string{"a"}},
+		{Name: "string literal", Input: "select ':a', 'it''s :b', :c", SQL: "select ':a', 'it''s :b', $1", Params: []string{"c"}},
+		{Name: "quoted identifier", Input: `select ":a" from t where x = :x`, SQL: `select ":a" from t where x = $1`, Params: []string{"x"}},
+		{Name: "dollar quoted", Input: "select $$:a$$, $tag$:b$tag$, :c", SQL: "select $$:a$$, $tag$:b$tag$, $1", Params: []string{"c"}},
+		{Name: "comments", Input: "select :a -- :b\n/* :c */ , :d", SQL: "select $1 -- :b\n/* :c */ , $2", Params: []string{"a", "d"}},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			nq := pgclient.ParseNamed(v.Input)
+			if nq.SQL != v.SQL {
+				t.Errorf("sql: got %q, expected %q", nq.SQL, v.SQL)
+			}
+			if !reflect.DeepEqual(nq.Names, v.Params) {
+				t.Errorf("names: got %v, expected %v", nq.Names, v.Params)
+			}
+		})
+	}
+}
+
+func TestNamedArgs(t *testing.T) {
+	args, err := pgclient.NamedArgs(structTest, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["_id_hash"] != structTest.IDHash || args["int_field"] != structTest.IntField {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if v, ok := args["string_pointer_field"]; !ok || v != nil {
+		t.Errorf("nil pointer should bind as nil, got %#v", v)
+	}
+
+	put, err := pgclient.DefaultPutText(structTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, params, err := pgclient.BindNamed(put, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 7 {
+		t.Errorf("expected 7 params, got %d:\n%s", len(params), query)
+	}
+}
+
+type keylessTest struct {
+	Name  string `db:"name"`
+	Value int    `db:"value"`
+}
+
+func TestPutText(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	cfg.Schema = "public"
+	var testCases = []struct {
+		Name   string
+		Value  any
+		Expect string
+	}{
+		{Name: "keyed", Value: raceA{}, Expect: "insert into \"public\".\"racea\" (\n\t\"id\",\n\t\"name\"\n) values (\n\t:id,\n\t:name\n) on conflict (\"id\") do nothing"},
+		{Name: "keyless", Value: keylessTest{}, Expect: "insert into \"public\".\"keylesstest\" (\n\t\"name\",\n\t\"value\"\n) values (\n\t:name,\n\t:value\n)"},
+	}
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			text, err := pgclient.TemplateToText(v.Value, pgclient.PGTemplates.Put, &cfg, pgclient.FuncMap, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(text); got != v.Expect {
+				t.Errorf("got:\n%s\nexpected:\n%s", got, v.Expect)
+			}
+		})
+	}
+}
+
+type namedArgsChild struct {
+	ParentID int64     `db:"parent_id" parentprimarykey:"true"`
+	Done     chan bool `db:"done"` // json can't encode it
+	Value    string    `db:"value"`
+}
+
+type namedArgsParent struct {
+	ID       int64            `db:"id" primarykey:"true"`
+	Name     string           `db:"name"`
+	Children []namedArgsChild `struct:"true"`
+}
+
+func TestNamedArgsSkipsChildren(t *testing.T) {
+	args, err := pgclient.NamedArgs(namedArgsParent{ID: 1, Name: "a", Children: []namedArgsChild{{Done: make(chan bool)}}}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]any{"id": int64(1), "name": "a"}; !reflect.DeepEqual(args, expect) {
+		t.Errorf("got %#v, expected %#v", args, expect)
+	}
+}
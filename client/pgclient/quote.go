@@ -0,0 +1,239 @@
+package pgclient
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// MaxIdentifierLength is the longest identifier postgres keeps, longer ones are silently truncated
+const MaxIdentifierLength = 63
+
+// ErrUnsafeIdentifier and ErrUnsafeExpression are returned, wrapped, for names, types and
+// expressions from tags or config that are rejected before they reach generated SQL
+var (
+	ErrUnsafeIdentifier = errors.New("unsafe identifier")
+	ErrUnsafeExpression = errors.New("unsafe expression")
+)
+
+// IdentifierPattern is what QuoteIdentifier accepts: letters, digits, underscores, dollar signs,
+// hyphens and single inner spaces, not starting with a digit. Dots are rejected, qualified names are
+// quoted part by part. It can be loosened, quoting keeps any identifier safe except one containing NUL.
+var IdentifierPattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_$-]*( [\p{L}\p{N}_$-]+)*$`)
+
+// TypePattern is what ColumnDefinitions and StructColumns accept as a column type: an optionally
+// schema qualified name of words, an optional modifier such as (10,2), and any number of [] suffixes
+var TypePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?( [A-Za-z_][A-Za-z0-9_]*)*( ?\( *[0-9]+ *(, *[0-9]+ *)?\))?( [A-Za-z_][A-Za-z0-9_ ]*)?(\[[0-9]*\])*$`)
+
+// ValidateIdentifier returns an error wrapping ErrUnsafeIdentifier unless name matches IdentifierPattern
+// and is at most MaxIdentifierLength bytes
+func ValidateIdentifier(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty", ErrUnsafeIdentifier)
+	case len(name) > MaxIdentifierLength:
+		return fmt.Errorf("%w: %q is longer than %d bytes", ErrUnsafeIdentifier, name, MaxIdentifierLength)
+	case strings.ContainsRune(name, 0) || !IdentifierPattern.MatchString(name):
+		return fmt.Errorf("%w: %q", ErrUnsafeIdentifier, name)
+	}
+	return nil
+}
+
+// QuoteIdentifier validates name (see ValidateIdentifier) and double quotes it, so reserved words such as
+// order or user, and hyphenated names, can be used as is. Names are not case folded, the templates lowercase
+// them first so they match tables created before quoting was added.
+func QuoteIdentifier(name string) (string, error) {
+	if err := ValidateIdentifier(name); err != nil {
+		return "", err
+	}
+	return quoteIdentifier(name), nil
+}
+
+// QuoteIdentifiers applies QuoteIdentifier to each name
+func QuoteIdentifiers(names []string) ([]string, error) {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		q, err := QuoteIdentifier(name)
+		if err != nil {
+			return nil, err
+		}
+		quoted = append(quoted, q)
+	}
+	return quoted, nil
+}
+
+// QuoteQualified validates and quotes each part of a qualified name, skipping empty parts, and joins them with dots
+func QuoteQualified(parts ...string) (string, error) {
+	var quoted []string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		q, err := QuoteIdentifier(part)
+		if err != nil {
+			return "", err
+		}
+		quoted = append(quoted, q)
+	}
+	return strings.Join(quoted, "."), nil
+}
+
+// truncateIdentifier shortens generated names to MaxIdentifierLength bytes, without splitting a character
+func truncateIdentifier(name string) string {
+	if len(name) <= MaxIdentifierLength {
+		return name
+	}
+	i := MaxIdentifierLength
+	for i > 0 && !utf8.RuneStart(name[i]) {
+		i--
+	}
+	return name[:i]
+}
+
+// quoteIdentifier double quotes name, doubling any quotes in it
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral single quotes value as a string constant, doubling any single quotes.
+// Values with backslashes use the E'...' form with backslashes doubled, so the result is
+// the same whatever standard_conforming_strings is set to.
+func QuoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, "\x00", "")
+	quoted := "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	if strings.Contains(value, `\`) {
+		quoted = "E" + strings.ReplaceAll(quoted, `\`, `\\`)
+	}
+	return quoted
+}
+
+// TableIdentifier returns the quoted, schema qualified, lowercased table name the templates use for str
+func TableIdentifier(str *meta.Struct, cfg client.TemplatorConfig) (string, error) {
+	var parts []string
+	for _, ns := range str.NameSpace {
+		parts = append(parts, strings.ToLower(ns))
+	}
+	parts = append(parts, strings.ToLower(str.TagName(cfg.TableNameTags)))
+	return QuoteQualified(parts...)
+}
+
+// TempTableIdentifier returns the quoted TempTableName
+func TempTableIdentifier(str *meta.Struct, cfg client.TemplatorConfig) (string, error) {
+	return QuoteIdentifier(TempTableName(*str, cfg))
+}
+
+// NamedParameters returns a :name placeholder for each name, see ParseNamed. Names that aren't
+// plain words are quoted, eg :"my-column".
+func NamedParameters(names []string) []string {
+	params := make([]string, 0, len(names))
+	for _, name := range names {
+		if isName(name) {
+			params = append(params, ":"+name)
+		} else {
+			params = append(params, ":"+quoteIdentifier(name))
+		}
+	}
+	return params
+}
+
+// isName returns true if s can be a plain :name placeholder
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateType returns an error wrapping ErrUnsafeIdentifier unless t matches TypePattern
+func ValidateType(t string) error {
+	if !TypePattern.MatchString(strings.TrimSpace(t)) {
+		return fmt.Errorf("%w: type %q", ErrUnsafeIdentifier, t)
+	}
+	return nil
+}
+
+// SafeExpression returns expr if it can be embedded in DDL as a DEFAULT, CHECK, GENERATED, or index
+// predicate: quotes and parentheses must be balanced, and it may not contain statement separators or
+// comments outside of string constants. Escape string constants, E'...', may use backslash escapes.
+// Otherwise it returns an error wrapping ErrUnsafeExpression.
+func SafeExpression(expr string) (string, error) {
+	unsafe := func(reason string) (string, error) {
+		return "", fmt.Errorf("%w: %s in %q", ErrUnsafeExpression, reason, expr)
+	}
+	depth := 0
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == '\'' && escapeString(expr, i):
+			end := escapedEnd(expr, i)
+			if end < 0 {
+				return unsafe("unterminated quote")
+			}
+			i = end
+			continue
+		case ch == '\'' || ch == '"':
+			// doubled quotes are escapes, so a terminated constant has an even number of quotes
+			end := quotedEnd(expr, i, ch)
+			if strings.Count(expr[i:end], string(ch))%2 != 0 {
+				return unsafe("unterminated quote")
+			}
+			i = end
+			continue
+		case ch == '$' && dollarTag(expr[i:]) != "":
+			return unsafe("dollar quote")
+		case ch == ';':
+			return unsafe("semicolon")
+		case ch == 0:
+			return unsafe("NUL")
+		case strings.HasPrefix(expr[i:], "--"), strings.HasPrefix(expr[i:], "/*"):
+			return unsafe("comment")
+		case ch == '(':
+			depth++
+		case ch == ')':
+			if depth--; depth < 0 {
+				return unsafe("unbalanced parentheses")
+			}
+		}
+		i++
+	}
+	if depth != 0 {
+		return unsafe("unbalanced parentheses")
+	}
+	return expr, nil
+}
+
+// escapeString returns true if the quote at s[start] opens an escape string constant, E'...' or e'...'
+func escapeString(s string, start int) bool {
+	if start == 0 || (s[start-1] != 'E' && s[start-1] != 'e') {
+		return false
+	}
+	return start == 1 || !isNameChar(s[start-2])
+}
+
+// escapedEnd returns the index just past the quote that closes the escape string constant at start,
+// where backslashes escape the next character and doubled quotes are escapes, or -1 if it isn't closed
+func escapedEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] != '\'':
+			// part of the constant
+		case i+1 < len(s) && s[i+1] == '\'':
+			i++
+		default:
+			return i + 1
+		}
+	}
+	return -1
+}
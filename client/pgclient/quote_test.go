@@ -0,0 +1,184 @@
+package pgclient_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	var testCases = []struct {
+		Input  string
+		Expect string
+		Err    bool
+	}{
+		{Input: "id", Expect: `"id"`},
+		{Input: "order", Expect: `"order"`},
+		{Input: "user", Expect: `"user"`},
+		{Input: "MixedCase", Expect: `"MixedCase"`},
+		{Input: "my-column", Expect: `"my-column"`},
+		{Input: "first name", Expect: `"first name"`},
+		{Input: "_hash$1", Expect: `"_hash$1"`},
+		{Input: "", Err: true},
+		{Input: "1st", Err: true},
+		{Input: `na"me`, Err: true},
+		{Input: "name; drop table users", Err: true},
+		{Input: "schema.table", Err: true},
+		{Input: "name ", Err: true},
+		{Input: strings.Repeat("a", 64), Err: true},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Input, func(t *testing.T) {
+			got, err := pgclient.QuoteIdentifier(v.Input)
+			switch {
+			case v.Err && !errors.Is(err, pgclient.ErrUnsafeIdentifier):
+				t.Errorf("expected ErrUnsafeIdentifier, got %q, %v", got, err)
+			case !v.Err && (err != nil || got != v.Expect):
+				t.Errorf("got %q, %v, expected %q", got, err, v.Expect)
+			}
+		})
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	var testCases = []struct {
+		Input  string
+		Expect string
+	}{
+		{Input: "", Expect: `''`},
+		{Input: "plain", Expect: `'plain'`},
+		{Input: "it's", Expect: `'it''s'`},
+		{Input: `back\slash`, Expect: `E'back\\slash'`},
+		{Input: `'; drop table users; --`, Expect: `'''; drop table users; --'`},
+	}
+
+	for _, v := range testCases {
+		if got := pgclient.QuoteLiteral(v.Input); got != v.Expect {
+			t.Errorf("QuoteLiteral(%q): got %s, expected %s", v.Input, got, v.Expect)
+		}
+	}
+}
+
+func TestSafeExpression(t *testing.T) {
+	var testCases = []struct {
+		Input string
+		Err   bool
+	}{
+		{Input: "now()"},
+		{Input: "name <> ''"},
+		{Input: "status in ('a;b', 'it''s')"},
+		{Input: `length("order") > 0`},
+		{Input: "1); drop table users; --", Err: true},
+		{Input: "0 -- comment", Err: true},
+		{Input: "0 /* comment */", Err: true},
+		{Input: "length(name", Err: true},
+		{Input: "name)", Err: true},
+		{Input: "'unterminated", Err: true},
+		{Input: "'doubled''", Err: true},
+		{Input: "'doubled'''"},
+		{Input: "$$body$$", Err: true},
+		{Input: `E'it\'s'`},
+		{Input: `e'\''`},
+		{Input: `E'\\' || 'x'`},
+		{Input: `E'\'; still quoted'`},
+		{Input: `E'\'' || ''; drop table users; --'`, Err: true},
+		{Input: `E'\\'; drop table users; --'`, Err: true},
+		{Input: `E'unterminated\'`, Err: true},
+		{Input: `name <> 'E'`},
+	}
+
+	for _, v := range testCases {
+		_, err := pgclient.SafeExpression(v.Input)
+		if v.Err != errors.Is(err, pgclient.ErrUnsafeExpression) {
+			t.Errorf("SafeExpression(%q): got %v, expected error %v", v.Input, err, v.Err)
+		}
+	}
+}
+
+func TestValidateType(t *testing.T) {
+	for _, valid := range []string{
+		"text", "bigint", "text[]", "double precision", "timestamp with time zone", "numeric(10,2)",
+		"character varying(20)[]", "timestamp(3) with time zone", "public.address", "int[][]",
+	} {
+		if err := pgclient.ValidateType(valid); err != nil {
+			t.Errorf("ValidateType(%q): %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "text, injected text", "text); drop table users; --", "text default 'x'"} {
+		if err := pgclient.ValidateType(invalid); err == nil {
+			t.Errorf("ValidateType(%q): expected an error", invalid)
+		}
+	}
+}
+
+type reservedTest struct {
+	ID     int64  `db:"id" primarykey:"true"`
+	Order  int    `db:"order"`
+	User   string `db:"User"`
+	Hyphen string `db:"my-column"`
+}
+
+func TestQuotedTemplates(t *testing.T) {
+	for name, tpl := range map[string]string{
+		"CreateTable":       pgclient.PGTemplates.CreateTable,
+		"Put":               pgclient.PGTemplates.Put,
+		"PutReplaceChanges": pgclient.PGTemplates.PutReplaceChanges,
+		"PutTempToTable":    pgclient.PGTemplates.PutTempToTable,
+		"Get":               pgclient.PGTemplates.Get,
+	} {
+		text, err := pgclient.TemplateToText(reservedTest{}, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, expect := range []string{`"order"`, `"user"`, `"my-column"`, `"reservedtest"`} {
+			if !strings.Contains(text, expect) {
+				t.Errorf("%s: expected %s in:\n%s", name, expect, text)
+			}
+		}
+	}
+
+	text, err := pgclient.DefaultPutText(reservedTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := pgclient.NamedArgs(reservedTest{ID: 1, Order: 2, User: "u", Hyphen: "h"}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := pgclient.BindNamed(text, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []any{int64(1), 2, "u", "h"}; !reflect.DeepEqual(params, expect) {
+		t.Errorf("params: got %#v, expected %#v", params, expect)
+	}
+}
+
+type unsafeNameTest struct {
+	ID int64 `db:"id; drop table users" primarykey:"true"`
+}
+
+type unsafeCheckTest struct {
+	ID int64 `db:"id" check:"id > 0); drop table users; --"`
+}
+
+func TestUnsafeTags(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	cfg.Table = `users"; drop table users; --`
+	for name, render := range map[string]func() (string, error){
+		"column": func() (string, error) { return pgclient.DefaultCreateTableText(unsafeNameTest{}) },
+		"put":    func() (string, error) { return pgclient.DefaultPutText(unsafeNameTest{}) },
+		"check":  func() (string, error) { return pgclient.DefaultCreateTableText(unsafeCheckTest{}) },
+		"table": func() (string, error) {
+			return pgclient.TemplateToText(reservedTest{}, pgclient.PGTemplates.Get, &cfg, pgclient.FuncMap, nil)
+		},
+	} {
+		if text, err := render(); err == nil {
+			t.Errorf("%s: expected an error, got:\n%s", name, text)
+		}
+	}
+}
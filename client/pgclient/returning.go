@@ -0,0 +1,124 @@
+package pgclient
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReturningFields returns the ColumnFields read back by PutOptions.Returning: those tagged with
+// ReturningTag, and those the database fills in, DefaultTag, GeneratedTag and ManagedFields
+func ReturningFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	var fields meta.Fields
+	managed := managedTags(cfg)
+	for _, field := range ColumnFields(str, cfg) {
+		switch {
+		case cfg.ReturningTag != "" && field.HasTagTrue(cfg.ReturningTag),
+			field.NonEmptyTagValue(cfg.DefaultTag) != "",
+			field.NonEmptyTagValue(cfg.GeneratedTag) != "",
+			field.HasTagTrue(managed):
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// DefaultFields returns the WriteFields Put writes as DEFAULT when they're zero: the managed ones (see
+// ManagedFields), and pointer fields with a DefaultTag, which are unset when nil. Other fields with a
+// DefaultTag are always written, false, 0 and "" are values too.
+func DefaultFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	var fields meta.Fields
+	managed := managedTags(cfg)
+	for _, field := range WriteFields(str, cfg) {
+		if (field.Pointer() && field.NonEmptyTagValue(cfg.DefaultTag) != "") || field.HasTagTrue(managed) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// ValueParameters is NamedParameters, except names in defaults are the DEFAULT keyword
+func ValueParameters(defaults []string, names []string) []string {
+	params := NamedParameters(names)
+	for i, name := range names {
+		if slices.Contains(defaults, name) {
+			params[i] = "DEFAULT"
+		}
+	}
+	return params
+}
+
+// ZeroDefaults returns the column names of row's zero valued fields, Put writes them as DEFAULT
+func ZeroDefaults(row any, fields meta.Fields, cfg client.TemplatorConfig) []string {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var defaults []string
+	for i, column := range ColumnNames(fields, cfg) {
+		if fv, err := rv.FieldByIndexErr(fields[i].StructField.Index); err == nil && fv.IsZero() {
+			defaults = append(defaults, column)
+		}
+	}
+	return defaults
+}
+
+// addressableElements returns the structs in value that returned values can be written to: a pointer to
+// a struct, a slice of structs or struct pointers, or a pointer to either
+func addressableElements(value any) ([]reflect.Value, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		return []reflect.Value{rv.Elem()}, nil
+	}
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot write returned values to %T, pass a pointer or a slice", value)
+	}
+
+	elements := make([]reflect.Value, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		elem := rv.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				return nil, fmt.Errorf("cannot write returned values to nil element %d of %T", i, value)
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("cannot write returned values to %s elements of %T", elem.Type(), value)
+		}
+		elements = append(elements, elem)
+	}
+	return elements, nil
+}
+
+// scanReturning scans the rows returned for one element into it, and returns them as a meta.SQLResult
+// with the values keyed by column name. A row skipped by on conflict do nothing returns no values.
+func scanReturning(rows pgx.Rows, elem reflect.Value, index map[string][]int) (meta.SQLResult, error) {
+	defer rows.Close()
+	columns, json := scanColumns(rows.FieldDescriptions(), index)
+
+	var values map[string]any
+	for rows.Next() {
+		if err := rows.Scan(scanTargets(elem, columns, json)...); err != nil {
+			return meta.SQLResult{}, err
+		}
+		values = map[string]any{}
+		for i, fd := range rows.FieldDescriptions() {
+			if columns[i] != nil {
+				values[strings.ToLower(fd.Name)] = elem.FieldByIndex(columns[i]).Interface()
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return meta.SQLResult{}, err
+	}
+	return meta.SQLResult{Result: Result{rows.CommandTag()}, Values: values}, nil
+}
@@ -0,0 +1,126 @@
+package pgclient_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type returningTest struct {
+	ID        int64      `db:"id" primarykey:"true" identity:"true"`
+	Name      string     `db:"name"`
+	Version   int        `db:"version" returning:"true"`
+	CreatedAt *time.Time `db:"created_at" default:"now()"`
+	NameLen   int        `db:"name_len" generated:"length(name)"`
+}
+
+func TestReturningFields(t *testing.T) {
+	str, err := meta.ToStruct(returningTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := pgclient.TemplateConfig
+	if got := pgclient.ColumnNames(pgclient.ReturningFields(str, cfg), cfg); !reflect.DeepEqual(got, []string{"id", "version", "created_at", "name_len"}) {
+		t.Errorf("ReturningFields: got %v", got)
+	}
+	if got := pgclient.ColumnNames(pgclient.DefaultFields(str, cfg), cfg); !reflect.DeepEqual(got, []string{"id", "created_at"}) {
+		t.Errorf("DefaultFields: got %v", got)
+	}
+}
+
+type defaultTest struct {
+	ID      int64   `db:"id" primarykey:"true"`
+	Active  bool    `db:"active" default:"true"`
+	Count   int     `db:"count" default:"10"`
+	Label   string  `db:"label" default:"'none'"`
+	Comment *string `db:"comment" default:"'none'"`
+}
+
+func TestZeroDefaults(t *testing.T) {
+	str, err := meta.ToStruct(defaultTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := pgclient.TemplateConfig
+	fields := pgclient.DefaultFields(str, cfg)
+	if got := pgclient.ColumnNames(fields, cfg); !reflect.DeepEqual(got, []string{"comment"}) {
+		t.Errorf("DefaultFields: got %v", got)
+	}
+
+	comment := ""
+	var testCases = []struct {
+		Name   string
+		Row    defaultTest
+		Expect []string
+	}{
+		{Name: "zero values are written", Row: defaultTest{}, Expect: []string{"comment"}},
+		{Name: "false 0 and empty string", Row: defaultTest{Active: false, Count: 0, Label: ""}, Expect: []string{"comment"}},
+		{Name: "empty string pointer", Row: defaultTest{Comment: &comment}},
+	}
+	for _, v := range testCases {
+		if got := pgclient.ZeroDefaults(v.Row, fields, cfg); !reflect.DeepEqual(got, v.Expect) {
+			t.Errorf("%s: got %v, expected %v", v.Name, got, v.Expect)
+		}
+	}
+
+	args, err := pgclient.NamedArgs(defaultTest{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["active"] != false || args["count"] != 0 || args["label"] != "" {
+		t.Errorf("expected false, 0 and \"\" to be written as is, got %v", args)
+	}
+}
+
+func TestReturningText(t *testing.T) {
+	for _, strategy := range []meta.UpdateStrategy{meta.AppendChanges, meta.AppendAll, meta.ReplaceChanges} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			text, err := pgclient.TemplateToText(returningTest{}, pgclient.PGTemplates.PutStrategy(strategy), &pgclient.TemplateConfig, pgclient.FuncMap, map[string]any{
+				"defaults":  []string{"id"},
+				"returning": []string{"id", "created_at"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(strings.TrimSpace(text), `returning "id", "created_at"`) {
+				t.Errorf("expected a returning clause:\n%s", text)
+			}
+			nq := pgclient.ParseNamed(text)
+			if !reflect.DeepEqual(nq.Names, []string{"name", "version", "created_at"}) {
+				t.Errorf("expected id to be DEFAULT, got parameters %v:\n%s", nq.Names, text)
+			}
+			if !strings.Contains(text, "DEFAULT,") {
+				t.Errorf("expected DEFAULT for id:\n%s", text)
+			}
+		})
+	}
+
+	text, err := pgclient.DefaultPutText(returningTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text, "returning \"") || strings.Contains(text, "DEFAULT") {
+		t.Errorf("unexpected returning or DEFAULT without data:\n%s", text)
+	}
+}
+
+func TestPutReturningValue(t *testing.T) {
+	c := pgclient.NewClient()
+	for name, value := range map[string]any{
+		"struct":  returningTest{},
+		"ints":    []int{1},
+		"nil ptr": []*returningTest{nil},
+	} {
+		if _, err := c.Put(context.Background(), value, pgclient.PutOptions{Returning: true}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := c.Put(context.Background(), []returningTest{{}}, pgclient.PutOptions{Returning: true, Strategy: meta.ReplaceAll}); err == nil {
+		t.Error("expected an error for ReplaceAll")
+	}
+}
@@ -0,0 +1,103 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// DefaultPageSize is the number of rows Rows reads per page when GetOptions.PageSize isn't set
+var DefaultPageSize = 1000
+
+// Rows streams T's table using the GetPage template, paging by its PrimaryKeyFields
+// (keyset pagination) so tables larger than memory can be processed. Each page is read fully
+// before it is yielded, so the client can be used inside the loop. GetOptions.Limit caps the
+// total number of rows, and Example and Where filter them. Iteration stops after the first error.
+func Rows[T any](ctx context.Context, c *Client, opts ...GetOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		o := getOptions(opts)
+		if o.PageSize <= 0 {
+			o.PageSize = DefaultPageSize
+		}
+
+		str, err := meta.ToStruct(zero)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if len(PrimaryKeyFields(str, c.Config.Template)) == 0 {
+			yield(zero, fmt.Errorf("%s has no %s fields to page by", str.Name, c.Config.Template.PrimaryKeyTag))
+			return
+		}
+
+		data, filterArgs, err := o.data(zero, c.Config.Template)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		// render the first page and the following pages once
+		pages := [2]NamedQuery{}
+		for i, after := range []bool{false, true} {
+			sqlText, err := c.TemplateToText(zero, c.Templator.GetPage, map[string]any{
+				"after":    after,
+				"pagesize": o.PageSize,
+				"where":    data["where"],
+			})
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			pages[i] = ParseNamed(sqlText)
+		}
+
+		var count int
+		var after map[string]any
+		args := maps.Clone(filterArgs)
+		for {
+			query := pages[0]
+			if after != nil {
+				query = pages[1]
+				maps.Copy(args, after)
+			}
+			params, err := query.Bind(args)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			rows, err := c.DB().Query(ctx, query.SQL, params...)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			page, err := CollectRows[T](rows, c.Config.Template)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, value := range page {
+				if !yield(value, nil) {
+					return
+				}
+				count++
+				if o.Limit > 0 && count >= o.Limit {
+					return
+				}
+			}
+			if len(page) < o.PageSize {
+				return
+			}
+
+			after, err = NamedArgs(page[len(page)-1], c.Config.Template)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
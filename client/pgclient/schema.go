@@ -0,0 +1,318 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// Column describes a table column, either introspected from the database or derived from a struct field
+type Column struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	PrimaryKey bool
+	Position   int
+	Default    string // from DefaultTag or ManagedDefault, struct columns only
+	Check      string // from CheckTag, struct columns only
+}
+
+// ColumnChange pairs a column's current (From) and desired (To) definitions
+type ColumnChange struct {
+	From Column
+	To   Column
+}
+
+// SchemaDiff is the difference between a struct's columns and its existing table
+type SchemaDiff struct {
+	Table   string // schema qualified table name
+	Added   []Column
+	Removed []Column
+	Changed []ColumnChange // type changes
+	Renamed []ColumnChange // matched through TemplatorConfig.PreviousNameTag
+}
+
+// Empty returns true if there are no differences
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed)+len(d.Renamed) == 0
+}
+
+// Statements renders the diff as ALTER TABLE statements: renames first, then
+// added columns, type changes, and finally removed columns. Names are quoted.
+func (d SchemaDiff) Statements() []string {
+	var parts []string
+	for _, part := range strings.Split(d.Table, ".") {
+		parts = append(parts, quoteIdentifier(part))
+	}
+	table := strings.Join(parts, ".")
+
+	var statements []string
+	for _, c := range d.Renamed {
+		statements = append(statements, fmt.Sprintf("alter table %s rename column %s to %s", table, quoteIdentifier(c.From.Name), quoteIdentifier(c.To.Name)))
+	}
+	for _, c := range d.Added {
+		definition := c.Type
+		if c.Default != "" {
+			// existing rows get the default, so NOT NULL can be added with it
+			if !c.Nullable {
+				definition += " NOT NULL"
+			}
+			definition += " DEFAULT " + c.Default
+		}
+		if c.Check != "" {
+			definition += fmt.Sprintf(" CHECK ( %s )", c.Check)
+		}
+		statements = append(statements, fmt.Sprintf("alter table %s add column if not exists %s %s", table, quoteIdentifier(c.Name), definition))
+	}
+	for _, c := range d.Changed {
+		name := quoteIdentifier(c.To.Name)
+		statements = append(statements, fmt.Sprintf("alter table %s alter column %s type %s using %s::%s", table, name, c.To.Type, name, c.To.Type))
+	}
+	for _, c := range d.Removed {
+		statements = append(statements, fmt.Sprintf("alter table %s drop column if exists %s", table, quoteIdentifier(c.Name)))
+	}
+	return statements
+}
+
+// String returns the statements as a single script for review
+func (d SchemaDiff) String() string {
+	statements := d.Statements()
+	if len(statements) == 0 {
+		return ""
+	}
+	return strings.Join(statements, ";\n") + ";\n"
+}
+
+// TableName returns the lowercased schema and table name the templates use for value
+func TableName(value any, cfg client.TemplatorConfig) (string, string, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return "", "", err
+	}
+	ConfigureStruct(&str, cfg)
+	return strings.ToLower(str.LastNameSpace()), strings.ToLower(str.TagName(cfg.TableNameTags)), nil
+}
+
+// ColumnTypes returns the column type for each field, the same way CreateTable does, see FieldPGTypes
+func ColumnTypes(fields meta.Fields, cfg client.TemplatorConfig) []string {
+	return FieldPGTypes(fields, cfg)
+}
+
+// StructColumns returns the columns CreateTable would create for value, with the same nullability
+// (see NotNull), defaults and checks as ColumnDefinitions. Names must pass ValidateIdentifier, types
+// ValidateType and expressions SafeExpression, since they're used in Statements.
+func StructColumns(value any, cfg client.TemplatorConfig) ([]Column, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := ColumnFields(str, cfg)
+	names := ColumnNames(fields, cfg)
+	types := ColumnTypes(fields, cfg)
+
+	var columns []Column
+	for i, field := range fields {
+		if err := ValidateIdentifier(names[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		if err := ValidateType(types[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		column := Column{
+			Name:       names[i],
+			Type:       types[i],
+			PrimaryKey: field.HasTagTrue(cfg.PrimaryKeyTag, cfg.ValidFromTag),
+			Position:   i + 1,
+		}
+		switch {
+		case field.NonEmptyTagValue(cfg.GeneratedTag) != "":
+			column.Nullable = !column.PrimaryKey
+		case cfg.IdentityTag != "" && field.HasTagTrue(cfg.IdentityTag):
+			// identity columns are implicitly NOT NULL
+		default:
+			column.Nullable = !column.PrimaryKey && !NotNull(field, cfg)
+			column.Default = field.NonEmptyTagValue(cfg.DefaultTag)
+			if column.Default == "" {
+				column.Default = ManagedDefault(field, cfg)
+			}
+			column.Check = field.NonEmptyTagValue(cfg.CheckTag)
+		}
+		for _, expression := range []string{column.Default, column.Check} {
+			if _, err := SafeExpression(expression); expression != "" && err != nil {
+				return nil, fmt.Errorf("%s: %w", field.Name, err)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Columns introspects information_schema for the columns of value's table
+func (c *Client) Columns(ctx context.Context, value any) ([]Column, error) {
+	schema, table, err := TableName(value, c.Config.Template)
+	if err != nil {
+		return nil, err
+	}
+	return c.TableColumns(ctx, schema, table)
+}
+
+// TableColumns introspects information_schema for the columns of schema.table. Numeric precision
+// isn't reported, since tag values can't contain commas.
+func (c *Client) TableColumns(ctx context.Context, schema, table string) ([]Column, error) {
+	rows, err := c.DB().Query(ctx, `
+		select
+			c.column_name,
+			case
+				when c.data_type = 'ARRAY' then ltrim(c.udt_name, '_') || '[]'
+				when c.data_type = 'USER-DEFINED' then c.udt_name
+				when c.character_maximum_length is not null then c.data_type || '(' || c.character_maximum_length || ')'
+				else c.data_type
+			end,
+			c.is_nullable = 'YES',
+			exists (
+				select 1
+				from information_schema.table_constraints tc
+				inner join information_schema.key_column_usage kcu
+					on kcu.constraint_schema = tc.constraint_schema
+					and kcu.constraint_name = tc.constraint_name
+				where tc.constraint_type = 'PRIMARY KEY'
+					and tc.table_schema = c.table_schema
+					and tc.table_name = c.table_name
+					and kcu.column_name = c.column_name
+			),
+			c.ordinal_position
+		from information_schema.columns c
+		where c.table_schema = $1 and c.table_name = $2
+		order by c.ordinal_position`,
+		schema, table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &column.PrimaryKey, &column.Position); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// Diff compares value's struct columns with its existing table. An empty table
+// (no columns) shows every column as added, use CreateTable instead.
+func (c *Client) Diff(ctx context.Context, value any) (SchemaDiff, error) {
+	existing, err := c.Columns(ctx, value)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	return DiffColumns(value, c.Config.Template, existing)
+}
+
+// ApplyDiff executes the diff's statements in a single transaction
+func (c *Client) ApplyDiff(ctx context.Context, diff SchemaDiff) error {
+	return c.WithTx(ctx, func(tx *Client) error {
+		for _, statement := range diff.Statements() {
+			if _, err := tx.DB().Exec(ctx, statement); err != nil {
+				return fmt.Errorf("%w: %s", err, statement)
+			}
+		}
+		return nil
+	})
+}
+
+// DiffColumns compares value's struct columns with existing ones. A field tagged with
+// PreviousNameTag is renamed when its previous name exists and its current name doesn't.
+func DiffColumns(value any, cfg client.TemplatorConfig, existing []Column) (SchemaDiff, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	ConfigureStruct(&str, cfg)
+	desired, err := StructColumns(value, cfg)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	fields := ColumnFields(str, cfg)
+
+	diff := SchemaDiff{Table: strings.ToLower(str.TagIdentifier(cfg.TableNameTags))}
+	current := map[string]Column{}
+	for _, column := range existing {
+		current[column.Name] = column
+	}
+	matched := map[string]bool{}
+
+	for i, column := range desired {
+		from, ok := current[column.Name]
+		if !ok {
+			previous := strings.ToLower(fields[i].NonEmptyTagValue(cfg.PreviousNameTag))
+			from, ok = current[previous]
+			if !ok || previous == "" || matched[previous] {
+				diff.Added = append(diff.Added, column)
+				continue
+			}
+			diff.Renamed = append(diff.Renamed, ColumnChange{From: from, To: column})
+		}
+		matched[from.Name] = true
+		if NormalizeType(from.Type) != NormalizeType(column.Type) {
+			diff.Changed = append(diff.Changed, ColumnChange{From: from, To: column})
+		}
+	}
+
+	for _, column := range existing {
+		if !matched[column.Name] {
+			diff.Removed = append(diff.Removed, column)
+		}
+	}
+	return diff, nil
+}
+
+// typeAliases maps postgres type aliases to the names information_schema reports
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+	"float":       "double precision",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"decimal":     "numeric",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+var typeModifier = regexp.MustCompile(`^([^(]+?)\s*(\(.*\))?$`)
+
+// NormalizeType lowercases a postgres type and resolves aliases, so eg
+// int8, bigint, and BIGINT compare equal. Modifiers and array suffixes are kept.
+func NormalizeType(t string) string {
+	t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+	var array string
+	for strings.HasSuffix(t, "[]") {
+		array += "[]"
+		t = strings.TrimSpace(strings.TrimSuffix(t, "[]"))
+	}
+	base, modifier := t, ""
+	if m := typeModifier.FindStringSubmatch(t); m != nil {
+		base, modifier = m[1], strings.ReplaceAll(m[2], " ", "")
+	}
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	return base + modifier + array
+}
@@ -0,0 +1,139 @@
+package pgclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type schemaTest struct {
+	ID      string  `db:"id" primarykey:"true"`
+	Name    string  `db:"full_name" was:"name"`
+	Count   int64   `db:"count"`
+	Score   float64 `db:"score"`
+	Comment string  `db:"comment"`
+}
+
+func TestDiffColumns(t *testing.T) {
+	existing := []pgclient.Column{
+		{Name: "id", Type: "text", Position: 1},
+		{Name: "name", Type: "text", Nullable: true, Position: 2},
+		{Name: "count", Type: "INT8", Nullable: true, Position: 3},
+		{Name: "score", Type: "integer", Nullable: true, Position: 4},
+		{Name: "legacy", Type: "text", Nullable: true, Position: 5},
+	}
+	diff, err := pgclient.DiffColumns(schemaTest{}, pgclient.TemplateConfig, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(columns []pgclient.Column) []string {
+		var out []string
+		for _, c := range columns {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	if got := names(diff.Added); !reflect.DeepEqual(got, []string{"comment"}) {
+		t.Errorf("added: got %v", got)
+	}
+	if got := names(diff.Removed); !reflect.DeepEqual(got, []string{"legacy"}) {
+		t.Errorf("removed: got %v", got)
+	}
+	if len(diff.Renamed) != 1 || diff.Renamed[0].From.Name != "name" || diff.Renamed[0].To.Name != "full_name" {
+		t.Errorf("renamed: got %v", diff.Renamed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].To.Name != "score" || diff.Changed[0].To.Type != "double precision" {
+		t.Errorf("changed: got %v", diff.Changed)
+	}
+
+	statements := diff.Statements()
+	for i, prefix := range []string{
+		`alter table "sample_schema"."schematest" rename column "name" to "full_name"`,
+		`alter table "sample_schema"."schematest" add column if not exists "comment" text`,
+		`alter table "sample_schema"."schematest" alter column "score" type double precision`,
+		`alter table "sample_schema"."schematest" drop column if exists "legacy"`,
+	} {
+		if i >= len(statements) || !strings.HasPrefix(statements[i], prefix) {
+			t.Errorf("statement %d: expected prefix %q, got:\n%s", i, prefix, diff)
+		}
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	for in, expect := range map[string]string{
+		"int8":                     "bigint",
+		"BIGINT":                   "bigint",
+		"varchar(20)":              "character varying(20)",
+		"character varying (20)":   "character varying(20)",
+		"timestamptz":              "timestamp with time zone",
+		"_int4[]":                  "_int4[]",
+		"int4[]":                   "integer[]",
+		"decimal(10, 2)":           "numeric(10,2)",
+		"double precision":         "double precision",
+		"timestamp with time zone": "timestamp with time zone",
+	} {
+		if got := pgclient.NormalizeType(in); got != expect {
+			t.Errorf("NormalizeType(%q): got %q, expected %q", in, got, expect)
+		}
+	}
+}
+
+type structColumnsTest struct {
+	ID      string  `db:"id" primarykey:"true"`
+	Name    string  `db:"name"`
+	Comment *string `db:"comment"`
+	Status  string  `db:"status" default:"'new'" check:"length(status) > 0"`
+	Seq     int64   `db:"seq" identity:"true"`
+	Length  int     `db:"length" generated:"length(name)"`
+}
+
+func TestStructColumns(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	columns, err := pgclient.StructColumns(structColumnsTest{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nullable := map[string]bool{}
+	for _, column := range columns {
+		nullable[column.Name] = column.Nullable
+	}
+	// the same as CreateTable declares them
+	expect := map[string]bool{"id": false, "name": false, "comment": true, "status": false, "seq": false, "length": true}
+	if !reflect.DeepEqual(nullable, expect) {
+		t.Errorf("nullable: got %v, expected %v", nullable, expect)
+	}
+	if columns[3].Default != "'new'" || columns[3].Check != "length(status) > 0" {
+		t.Errorf("status: got %+v", columns[3])
+	}
+
+	cfg.NullableByDefault = true
+	if columns, err = pgclient.StructColumns(structColumnsTest{}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !columns[1].Nullable || columns[0].Nullable {
+		t.Errorf("NullableByDefault: got %+v", columns)
+	}
+}
+
+func TestDiffAddedDefaults(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	cfg.Schema = "app"
+	diff, err := pgclient.DiffColumns(structColumnsTest{}, cfg, []pgclient.Column{
+		{Name: "id", Type: "text", Position: 1},
+		{Name: "name", Type: "text", Position: 2},
+		{Name: "comment", Type: "text", Nullable: true, Position: 3},
+		{Name: "seq", Type: "bigint", Position: 5},
+		{Name: "length", Type: "bigint", Nullable: true, Position: 6},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := diff.Statements()
+	expect := []string{`alter table "app"."structcolumnstest" add column if not exists "status" text NOT NULL DEFAULT 'new' CHECK ( length(status) > 0 )`}
+	if !reflect.DeepEqual(statements, expect) {
+		t.Errorf("got %q, expected %q", statements, expect)
+	}
+}
@@ -0,0 +1,83 @@
+package pgclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// lastStaged orders duplicate keys so distinct on keeps the last row staged
+const lastStaged = `order by tmp."_id_hash", tmp."_staged" desc`
+
+func TestUpdateStrategyTemplates(t *testing.T) {
+	var testCases = []struct {
+		Strategy meta.UpdateStrategy
+		Put      []string
+		Temp     []string
+		Keyless  []string // expected in both templates for a struct without a primary key
+	}{
+		{Strategy: meta.AppendChanges, Put: []string{"do nothing"}, Temp: []string{"do nothing", lastStaged}, Keyless: []string{"insert into"}},
+		{Strategy: meta.AppendAll, Put: []string{"values"}, Temp: []string{"select"}, Keyless: []string{"insert into"}},
+		{Strategy: meta.ReplaceChanges, Put: []string{"do update set", "is distinct from"}, Temp: []string{"do update set", "is distinct from", lastStaged}, Keyless: []string{"insert into"}},
+		// Put delegates ReplaceAll to Load, so it has no Put template of its own
+		{Strategy: meta.ReplaceAll, Temp: []string{"delete from", "not exists", "do update set", lastStaged}, Keyless: []string{"insert into"}},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Strategy.String(), func(t *testing.T) {
+			for tpl, expect := range map[string][]string{
+				pgclient.PGTemplates.PutStrategy(v.Strategy):            v.Put,
+				pgclient.PGTemplates.PutTempToTableStrategy(v.Strategy): v.Temp,
+			} {
+				text, err := pgclient.TemplateToText(structTest, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, e := range expect {
+					if !strings.Contains(text, e) {
+						t.Errorf("expected %q in:\n%s", e, text)
+					}
+				}
+				if v.Strategy == meta.AppendAll && strings.Contains(text, "on conflict") {
+					t.Errorf("unexpected on conflict in:\n%s", text)
+				}
+
+				keyless, err := pgclient.TemplateToText(keylessTest{}, tpl, &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, e := range v.Keyless {
+					if !strings.Contains(keyless, e) {
+						t.Errorf("keyless: expected %q in:\n%s", e, keyless)
+					}
+				}
+				for _, unexpected := range []string{"on conflict", "distinct on", "()", "( )"} {
+					if strings.Contains(keyless, unexpected) {
+						t.Errorf("keyless: unexpected %q in:\n%s", unexpected, keyless)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTempTableStaged(t *testing.T) {
+	text, err := pgclient.DefaultCreateTempTableText(structTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `add column "_staged" bigint generated always as identity`; !strings.Contains(text, expect) {
+		t.Errorf("expected %q in:\n%s", expect, text)
+	}
+	for _, strategy := range []meta.UpdateStrategy{meta.AppendChanges, meta.ReplaceChanges, meta.ReplaceAll, meta.History} {
+		text, err := pgclient.TemplateToText(historyTest{}, pgclient.PGTemplates.PutTempToTableStrategy(strategy), &pgclient.TemplateConfig, pgclient.FuncMap, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := `order by tmp."id", tmp."_staged" desc`; !strings.Contains(text, expect) {
+			t.Errorf("%s: expected %q in:\n%s", strategy, expect, text)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// Defaults for StreamOptions
+var (
+	DefaultStreamBatchSize     = 1000
+	DefaultStreamFlushInterval = 5 * time.Second
+)
+
+// StreamOptions are per call options for LoadStream and LoadSeq
+type StreamOptions struct {
+	PutOptions
+	BatchSize     int                     // maximum records per batch, defaults to DefaultStreamBatchSize
+	FlushInterval time.Duration           // longest a partial batch waits for more records, defaults to DefaultStreamFlushInterval, negative disables
+	OnBatch       func(BatchResult) error // called after each batch is loaded, returning an error stops the stream
+}
+
+// BatchResult reports a batch loaded by LoadStream or LoadSeq
+type BatchResult struct {
+	Batch    int // sequence number, starting at 0
+	Records  int
+	Results  meta.SQLResults
+	Duration time.Duration
+}
+
+// streamOptions returns the first of opts, with defaults filled in
+func (c *Client) streamOptions(opts []StreamOptions) StreamOptions {
+	var o StreamOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.PutOptions = c.putOptions([]PutOptions{o.PutOptions})
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultStreamBatchSize
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = DefaultStreamFlushInterval
+	}
+	return o
+}
+
+// LoadStream loads records from a channel in batches (see Load) until it is closed. A batch is loaded
+// when it reaches BatchSize, when FlushInterval passes after its first record, or when records closes.
+// Batches are loaded one at a time and records isn't read during a load, so producers block, rather
+// than buffer, when the database falls behind. It returns the results of all loaded batches, stopping
+// at the first error or when ctx is done; records left in the channel are not drained.
+func LoadStream[T any](ctx context.Context, c *Client, records <-chan T, opts ...StreamOptions) (meta.SQLResults, error) {
+	o := c.streamOptions(opts)
+	var results meta.SQLResults
+	n := 0
+	err := Batch(ctx, records, o.BatchSize, o.FlushInterval, func(batch []T) error {
+		start := time.Now()
+		result, err := c.Load(ctx, batch, o.PutOptions)
+		if err != nil {
+			return fmt.Errorf("loading batch %d: %w", n, err)
+		}
+		results = append(results, result...)
+		if o.OnBatch != nil {
+			if err := o.OnBatch(BatchResult{
+				Batch:    n,
+				Records:  len(batch),
+				Results:  result,
+				Duration: time.Since(start),
+			}); err != nil {
+				return err
+			}
+		}
+		n++
+		return nil
+	})
+	return results, err
+}
+
+// LoadSeq is LoadStream for an iterator. The iterator runs in its own goroutine, which stops
+// at its next record once LoadSeq returns.
+func LoadSeq[T any](ctx context.Context, c *Client, records iter.Seq[T], opts ...StreamOptions) (meta.SQLResults, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for record := range records {
+			select {
+			case ch <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return LoadStream(ctx, c, ch, opts...)
+}
+
+// Batch reads records into batches of up to size and calls fn with each, until records closes.
+// A partial batch is passed to fn once interval has passed since its first record; interval <= 0
+// only flushes full batches and the remainder. The batch slice is reused, so fn must not keep it.
+// Batch stops at fn's first error or when ctx is done.
+func Batch[T any](ctx context.Context, records <-chan T, size int, interval time.Duration, fn func([]T) error) error {
+	if size <= 0 {
+		size = 1
+	}
+	batch := make([]T, 0, size)
+	var timer *time.Timer
+	var flush <-chan time.Time
+	stop := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flush = nil, nil
+		}
+	}
+	defer stop()
+
+	send := func() error {
+		stop()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := fn(batch)
+		clear(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-flush:
+			timer, flush = nil, nil
+			if err := send(); err != nil {
+				return err
+			}
+		case record, ok := <-records:
+			if !ok {
+				return send()
+			}
+			batch = append(batch, record)
+			if len(batch) == 1 && interval > 0 {
+				timer = time.NewTimer(interval)
+				flush = timer.C
+			}
+			if len(batch) >= size {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
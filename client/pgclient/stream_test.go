@@ -0,0 +1,136 @@
+package pgclient_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+
+	var testCases = []struct {
+		Name     string
+		Records  []int
+		Size     int
+		Expect   [][]int
+		Interval time.Duration
+	}{
+		{Name: "empty", Records: nil, Size: 2, Expect: nil},
+		{Name: "exact", Records: []int{1, 2, 3, 4}, Size: 2, Expect: [][]int{{1, 2}, {3, 4}}},
+		{Name: "remainder", Records: []int{1, 2, 3}, Size: 2, Expect: [][]int{{1, 2}, {3}}},
+		{Name: "one batch", Records: []int{1, 2, 3}, Size: 10, Expect: [][]int{{1, 2, 3}}},
+		{Name: "zero size", Records: []int{1, 2}, Size: 0, Expect: [][]int{{1}, {2}}},
+		{Name: "interval", Records: []int{1, 2, 3}, Size: 2, Interval: time.Hour, Expect: [][]int{{1, 2}, {3}}},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			records := make(chan int, len(v.Records))
+			for _, r := range v.Records {
+				records <- r
+			}
+			close(records)
+
+			var got [][]int
+			err := pgclient.Batch(ctx, records, v.Size, v.Interval, func(batch []int) error {
+				got = append(got, append([]int{}, batch...))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v.Expect) {
+				t.Errorf("got %v, expected %v", got, v.Expect)
+			}
+		})
+	}
+
+	t.Run("flush interval", func(t *testing.T) {
+		records := make(chan int)
+		flushed := make(chan []int)
+		done := make(chan error)
+		go func() {
+			done <- pgclient.Batch(ctx, records, 10, 10*time.Millisecond, func(batch []int) error {
+				flushed <- append([]int{}, batch...)
+				return nil
+			})
+		}()
+
+		records <- 1
+		records <- 2
+		if got := <-flushed; !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Errorf("got %v, expected partial batch [1 2]", got)
+		}
+		records <- 3
+		close(records)
+		if got := <-flushed; !reflect.DeepEqual(got, []int{3}) {
+			t.Errorf("got %v, expected remainder [3]", got)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		records := make(chan int)
+		loading := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- pgclient.Batch(ctx, records, 1, 0, func(batch []int) error {
+				loading <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+
+		records <- 1
+		<-loading
+		select {
+		case records <- 2:
+			t.Error("records should not be read while a batch is loading")
+		case <-time.After(20 * time.Millisecond):
+		}
+		close(release)
+		records <- 2
+		<-loading
+		close(records)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		records := make(chan int, 4)
+		for i := range 4 {
+			records <- i
+		}
+		close(records)
+
+		calls := 0
+		err := pgclient.Batch(ctx, records, 1, 0, func(batch []int) error {
+			calls++
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) || calls != 1 {
+			t.Errorf("expected to stop at the first error, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := pgclient.Batch(ctx, make(chan int), 1, 0, func(batch []int) error {
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
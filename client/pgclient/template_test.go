@@ -0,0 +1,78 @@
+package pgclient_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type raceA struct {
+	ID   int64  `db:"id" primarykey:"true"`
+	Name string `db:"name"`
+}
+
+type raceB struct {
+	Key   string  `db:"key" primarykey:"true"`
+	Value float64 `db:"value"`
+}
+
+// TestTemplateToTextConcurrent renders different structs, configs, and data at once, run it with -race
+func TestTemplateToTextConcurrent(t *testing.T) {
+	type render struct {
+		value any
+		tpl   string
+		cfg   client.TemplatorConfig
+		data  map[string]any
+	}
+	var renders []render
+	for i, schema := range []string{"alpha", "beta", "gamma", "delta"} {
+		cfg := client.NewTemplatorConfig(pgclient.TemplateConfig, client.TemplatorConfig{Schema: schema})
+		for _, value := range []any{raceA{}, raceB{}} {
+			renders = append(renders,
+				render{value, pgclient.PGTemplates.CreateTable, cfg, nil},
+				render{value, pgclient.PGTemplates.Put, cfg, nil},
+				render{value, pgclient.PGTemplates.GetPage, cfg, map[string]any{"after": i%2 == 0, "pagesize": i + 1}},
+			)
+		}
+	}
+
+	expected := make([]string, len(renders))
+	for i, r := range renders {
+		text, err := pgclient.TemplateToText(r.value, r.tpl, &r.cfg, nil, r.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[i] = text
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(renders)*10)
+	for n := 0; n < 10; n++ {
+		for i, r := range renders {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c := pgclient.NewClient(client.Config{Template: r.cfg})
+				text, err := c.TemplateToText(r.value, r.tpl, r.data)
+				switch {
+				case err != nil:
+					errs <- err
+				case text != expected[i]:
+					errs <- fmt.Errorf("render %d:\ngot:\n%s\nexpected:\n%s", i, text, expected[i])
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if _, ok := pgclient.TemplateData["after"]; ok {
+		t.Error("call data leaked into TemplateData")
+	}
+}
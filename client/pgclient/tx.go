@@ -0,0 +1,34 @@
+package pgclient
+
+import (
+	"context"
+	"errors"
+)
+
+// WithTx runs fn with a copy of the client bound to a new transaction. The transaction is committed
+// if fn returns nil and rolled back if it returns an error or panics (the panic is then re-raised).
+// Calling WithTx on a client that is already in a transaction creates a savepoint, so nested calls
+// only roll back their own work.
+func (c *Client) WithTx(ctx context.Context, fn func(tx *Client) error) (err error) {
+	tx, err := c.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	txc := *c
+	txc.Tx = tx
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			panic(r)
+		}
+	}()
+
+	if err := fn(&txc); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
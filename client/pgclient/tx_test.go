@@ -0,0 +1,107 @@
+package pgclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeTx records how it was finished. Methods not overridden panic through the nil embedded interface.
+type fakeTx struct {
+	pgx.Tx
+	parent     *fakeTx
+	children   []*fakeTx
+	committed  bool
+	rolledBack bool
+}
+
+func (f *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	child := &fakeTx{parent: f}
+	f.children = append(f.children, child)
+	return child, nil
+}
+
+func (f *fakeTx) Commit(ctx context.Context) error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeTx) Rollback(ctx context.Context) error {
+	if !f.committed {
+		f.rolledBack = true
+	}
+	return nil
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	t.Run("commit", func(t *testing.T) {
+		root := &fakeTx{}
+		c := pgclient.NewClient()
+		c.Tx = root
+		err := c.WithTx(ctx, func(tx *pgclient.Client) error {
+			if tx.DB() != root.children[0] {
+				t.Error("tx client should use the new transaction")
+			}
+			return nil
+		})
+		if err != nil || !root.children[0].committed {
+			t.Errorf("expected commit, got err %v", err)
+		}
+		if c.Tx != root {
+			t.Error("WithTx should not modify the calling client")
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		root := &fakeTx{}
+		c := pgclient.NewClient()
+		c.Tx = root
+		err := c.WithTx(ctx, func(tx *pgclient.Client) error {
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) || !root.children[0].rolledBack {
+			t.Errorf("expected rollback and %v, got %v", errFailed, err)
+		}
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		root := &fakeTx{}
+		c := pgclient.NewClient()
+		c.Tx = root
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic to be re-raised")
+			}
+			if !root.children[0].rolledBack {
+				t.Error("expected rollback")
+			}
+		}()
+		c.WithTx(ctx, func(tx *pgclient.Client) error {
+			panic("boom")
+		})
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		root := &fakeTx{}
+		c := pgclient.NewClient()
+		c.Tx = root
+		err := c.WithTx(ctx, func(tx *pgclient.Client) error {
+			if err := tx.WithTx(ctx, func(inner *pgclient.Client) error {
+				return errFailed
+			}); !errors.Is(err, errFailed) {
+				t.Errorf("expected %v, got %v", errFailed, err)
+			}
+			return nil
+		})
+		outer := root.children[0]
+		if err != nil || !outer.committed || !outer.children[0].rolledBack {
+			t.Errorf("expected inner rollback and outer commit, got err %v", err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package pgclient
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+	"github.com/jackc/pgx/v5"
+)
+
+// FieldPGTypes returns each field's column type: its DataTypeTag, then its CompositeTag,
+// then PGType of its Go type
+func FieldPGTypes(fields meta.Fields, cfg client.TemplatorConfig) []string {
+	types := []string{}
+	for _, field := range fields {
+		switch {
+		case field.NonEmptyTagValue(cfg.DataTypeTag) != "":
+			types = append(types, field.NonEmptyTagValue(cfg.DataTypeTag))
+		case field.NonEmptyTagValue(cfg.CompositeTag) != "":
+			types = append(types, field.NonEmptyTagValue(cfg.CompositeTag))
+		default:
+			types = append(types, PGType(field.StructField.Type))
+		}
+	}
+	return types
+}
+
+// PGType resolves a Go type to a column type. Pointers and nullable wrappers (see Nullable) resolve
+// to their underlying type. Named types are looked up in GoToPGTypeMap, then TypeMap.To has the
+// exact type. Otherwise slices of scalars become arrays, []byte becomes bytea, and maps, structs,
+// interfaces, and slices of those become jsonb. Anything else is text.
+func PGType(t reflect.Type) string {
+	if t == nil {
+		return TypeMap.To[nil]
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if v, ok := meta.NullableType(t); ok {
+		return PGType(v)
+	}
+	if t.Kind() == reflect.Struct && Nullable(t) && t.NumField() > 0 {
+		// sql.NullString and friends hold their value in the first field
+		return PGType(t.Field(0).Type)
+	}
+
+	for _, name := range []string{t.String(), t.Name()} {
+		if pgtype, ok := GoToPGTypeMap[name]; ok && name != "" {
+			return pgtype
+		}
+	}
+	if pgtype, ok := TypeMap.To[t]; ok {
+		return pgtype
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint16, reflect.Uint32:
+		return "bigint"
+	case reflect.Uint, reflect.Uint64:
+		return "numeric"
+	case reflect.Float32, reflect.Float64:
+		return "double precision"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea"
+		}
+		switch elem := PGType(t.Elem()); {
+		case elem == "jsonb", strings.HasSuffix(elem, "[]"):
+			// postgres arrays are rectangular and untyped elements can't be arrays
+			return "jsonb"
+		default:
+			return elem + "[]"
+		}
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return "jsonb"
+	default:
+		return TypeMap.To[nil]
+	}
+}
+
+// isJSONType returns true for json and jsonb column types
+func isJSONType(pgtype string) bool {
+	switch NormalizeType(pgtype) {
+	case "json", "jsonb":
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonValue marshals v for a json column, so COPY and inserts encode it the same way.
+// Nil values stay nil, and strings and []byte are assumed to already be json.
+func jsonValue(v any) (any, error) {
+	switch rv := reflect.ValueOf(v); {
+	case v == nil:
+		return nil, nil
+	case (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice || rv.Kind() == reflect.Interface) && rv.IsNil():
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case json.RawMessage:
+		return v, nil
+	case []byte:
+		return json.RawMessage(v), nil
+	case string:
+		return json.RawMessage(v), nil
+	default:
+		b, err := json.Marshal(v)
+		return json.RawMessage(b), err
+	}
+}
+
+// RegisterTypes loads composite and enum types (and their array types) by name and registers them
+// with conn, so struct fields tagged with CompositeTag are encoded and scanned as composites
+func RegisterTypes(ctx context.Context, conn *pgx.Conn, names ...string) error {
+	for _, name := range names {
+		array := "_" + name
+		if i := strings.LastIndex(name, "."); i != -1 {
+			array = name[:i+1] + "_" + name[i+1:]
+		}
+		for _, typeName := range []string{name, array} {
+			t, err := conn.LoadType(ctx, typeName)
+			if err != nil {
+				return err
+			}
+			conn.TypeMap().RegisterType(t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package pgclient_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type typesAddress struct {
+	Street string
+	City   string
+}
+
+type typesTest struct {
+	ID       int64             `db:"id" primarykey:"true"`
+	Blob     []byte            `db:"blob"`
+	Names    []string          `db:"names"`
+	Counts   []int64           `db:"counts"`
+	Scores   *[]float64        `db:"scores"`
+	Labels   map[string]string `db:"labels"`
+	Address  typesAddress      `db:"address"`
+	Mailing  typesAddress      `db:"mailing" composite:"address"`
+	History  []typesAddress    `db:"history"`
+	Matrix   [][]int           `db:"matrix"`
+	Note     sql.NullString    `db:"note"`
+	Seen     []time.Time       `db:"seen"`
+	Raw      json.RawMessage   `db:"raw"`
+	Override []string          `db:"override" pgtype:"varchar(10)[]"`
+}
+
+func TestFieldPGTypes(t *testing.T) {
+	text, err := pgclient.DefaultCreateTableText(typesTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := pgclient.StructColumns(typesTest{}, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]string{}
+	for _, c := range cols {
+		types[c.Name] = c.Type
+	}
+	expect := map[string]string{
+		"id":       "bigint",
+		"blob":     "bytea",
+		"names":    "text[]",
+		"counts":   "bigint[]",
+		"scores":   "float8[]",
+		"labels":   "jsonb",
+		"address":  "jsonb",
+		"mailing":  "address",
+		"history":  "jsonb",
+		"matrix":   "jsonb",
+		"note":     "text",
+		"seen":     "timestamp with time zone[]",
+		"raw":      "jsonb",
+		"override": "varchar(10)[]",
+	}
+	if !reflect.DeepEqual(types, expect) {
+		t.Errorf("got types %v, expected %v\n%s", types, expect, text)
+	}
+}
+
+func TestNamedArgsJSON(t *testing.T) {
+	row := typesTest{
+		Labels:  map[string]string{"a": "b"},
+		Address: typesAddress{Street: "1 Main", City: "X"},
+		Mailing: typesAddress{Street: "2 Main"},
+	}
+	args, err := pgclient.NamedArgs(row, pgclient.TemplateConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := args["address"].(json.RawMessage); !ok || string(got) != `{"Street":"1 Main","City":"X"}` {
+		t.Errorf("address: got %#v", args["address"])
+	}
+	if got, ok := args["labels"].(json.RawMessage); !ok || string(got) != `{"a":"b"}` {
+		t.Errorf("labels: got %#v", args["labels"])
+	}
+	if args["history"] != nil {
+		t.Errorf("history: expected nil for a nil slice, got %#v", args["history"])
+	}
+	if _, ok := args["mailing"].(typesAddress); !ok {
+		t.Errorf("mailing: expected the composite struct as is, got %#v", args["mailing"])
+	}
+	if _, ok := args["names"].([]string); !ok {
+		t.Errorf("names: expected []string, got %#v", args["names"])
+	}
+}
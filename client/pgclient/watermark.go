@@ -0,0 +1,183 @@
+package pgclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client"
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+// Watermarks support incremental loads. A struct's LastInsertTags fields, eg an updated timestamp or an
+// increasing id, mark how far its table has been loaded: MostRecent reads their max() from the table, and
+// checkpoints save them by name in TemplatorConfig.CheckpointTable, so the next pull from a source only
+// requests records newer than the Watermark.
+
+// Checkpoint is a saved watermark, the row SaveCheckpoint writes to TemplatorConfig.CheckpointTable
+type Checkpoint struct {
+	Name      string          `db:"name" primarykey:"true"`
+	Table     string          `db:"table_name"` // schema qualified, unquoted
+	Watermark json.RawMessage `db:"watermark"`  // LastInsertFields values by column name, see MarshalWatermark
+	UpdatedAt time.Time       `db:"updated_at" updatedat:"true"`
+}
+
+// LastInsertFields returns str's ColumnFields tagged with one of LastInsertTags
+func LastInsertFields(str meta.Struct, cfg client.TemplatorConfig) meta.Fields {
+	return ColumnFields(str, cfg).WithTagTrue(cfg.LastInsertTags)
+}
+
+// lastInsertFields returns value's LastInsertFields, or an error when it has none
+func lastInsertFields(value any, cfg client.TemplatorConfig) (meta.Struct, meta.Fields, error) {
+	str, err := meta.ToStruct(value)
+	if err != nil {
+		return str, nil, err
+	}
+	fields := LastInsertFields(str, cfg)
+	if len(fields) == 0 {
+		return str, nil, fmt.Errorf("%s has no watermark, it needs a field tagged with one of %v", str.Name, cfg.LastInsertTags)
+	}
+	return str, fields, nil
+}
+
+// MarshalWatermark returns value's LastInsertFields as a json object keyed by column name
+func MarshalWatermark(value any, cfg client.TemplatorConfig) (json.RawMessage, error) {
+	_, fields, err := lastInsertFields(value, cfg)
+	if err != nil {
+		return nil, err
+	}
+	vm := meta.ToValueMap(fields, "")
+	watermark := map[string]any{}
+	for _, field := range fields {
+		watermark[strings.ToLower(field.TagName(cfg.FieldNameTags))] = nullableValue(vm[field.Name])
+	}
+	return json.Marshal(watermark)
+}
+
+// UnmarshalWatermark sets the LastInsertFields of the struct dst points to from a MarshalWatermark object,
+// fields missing from it are left as they are
+func UnmarshalWatermark(data []byte, dst any, cfg client.TemplatorConfig) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal a watermark into %T, it must be a pointer to a struct", dst)
+	}
+	_, fields, err := lastInsertFields(rv.Elem().Interface(), cfg)
+	if err != nil {
+		return err
+	}
+	var watermark map[string]json.RawMessage
+	if err := json.Unmarshal(data, &watermark); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		raw, ok := watermark[strings.ToLower(field.TagName(cfg.FieldNameTags))]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, rv.Elem().FieldByIndex(field.StructField.Index).Addr().Interface()); err != nil {
+			return fmt.Errorf("watermark %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// MostRecent returns a T with its LastInsertFields set to their max() in T's table, see the GetMostRecent
+// template. Other fields are zero, and ok is false when the table is empty.
+func MostRecent[T any](ctx context.Context, c *Client) (value T, ok bool, err error) {
+	if _, _, err := lastInsertFields(value, c.Config.Template); err != nil {
+		return value, false, err
+	}
+	sqlText, err := c.TemplateToText(value, c.Templator.GetMostRecent)
+	if err != nil {
+		return value, false, err
+	}
+	rows, err := c.DB().Query(ctx, sqlText)
+	if err != nil {
+		return value, false, err
+	}
+	values, err := CollectRows[T](rows, c.Config.Template)
+	if err != nil || len(values) == 0 {
+		return value, false, err
+	}
+	return values[0], true, nil
+}
+
+// checkpoint returns the Checkpoint for value's watermark, named for value's table when name is empty
+func (c *Client) checkpoint(name string, value any) (Checkpoint, error) {
+	if c.Config.Template.CheckpointTable == "" {
+		return Checkpoint{}, errors.New("checkpoints need a TemplatorConfig.CheckpointTable")
+	}
+	str, _, err := lastInsertFields(value, c.Config.Template)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	ConfigureStruct(&str, c.Config.Template)
+	cp := Checkpoint{Name: name, Table: strings.ToLower(str.TagIdentifier(c.Config.Template.TableNameTags))}
+	if cp.Name == "" {
+		cp.Name = cp.Table
+	}
+	return cp, nil
+}
+
+// SaveCheckpoint saves value's LastInsertFields as the checkpoint name, or value's table when name is empty,
+// replacing any earlier one. CheckpointTable is created the first time.
+func (c *Client) SaveCheckpoint(ctx context.Context, name string, value any) error {
+	cp, err := c.checkpoint(name, value)
+	if err != nil {
+		return err
+	}
+	if cp.Watermark, err = MarshalWatermark(value, c.Config.Template); err != nil {
+		return err
+	}
+	return c.putMetadata(ctx, c.Config.Template.CheckpointTable, &cp)
+}
+
+// GetCheckpoint returns a T with its LastInsertFields set from the checkpoint name, or T's table when name
+// is empty. ok is false when it hasn't been saved.
+func GetCheckpoint[T any](ctx context.Context, c *Client, name string) (value T, ok bool, err error) {
+	cp, err := c.checkpoint(name, value)
+	if err != nil {
+		return value, false, err
+	}
+
+	var saved []Checkpoint
+	mc := c.metadataClient(c.Config.Template.CheckpointTable)
+	err = mc.WithTx(ctx, func(tx *Client) error {
+		saved, err = Get[Checkpoint](ctx, tx, GetOptions{Where: meta.ValueMap{"name": cp.Name}})
+		return err
+	})
+	switch {
+	case undefinedTable(err):
+		return value, false, nil
+	case err != nil || len(saved) == 0:
+		return value, false, err
+	}
+	if err := UnmarshalWatermark(saved[0].Watermark, &value, c.Config.Template); err != nil {
+		return value, false, fmt.Errorf("checkpoint %s: %w", cp.Name, err)
+	}
+	return value, true, nil
+}
+
+// Watermark returns where the next incremental pull of T starts: the checkpoint name if it's been saved,
+// otherwise MostRecent. ok is false when there's neither, and everything should be pulled.
+func Watermark[T any](ctx context.Context, c *Client, name string) (T, bool, error) {
+	value, ok, err := GetCheckpoint[T](ctx, c, name)
+	if err != nil || ok {
+		return value, ok, err
+	}
+	return MostRecent[T](ctx, c)
+}
+
+// CheckpointMostRecent saves T's MostRecent as the checkpoint name and returns it, call it once a pull
+// has been loaded. Nothing is saved when T's table is empty.
+func CheckpointMostRecent[T any](ctx context.Context, c *Client, name string) (T, bool, error) {
+	value, ok, err := MostRecent[T](ctx, c)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	return value, true, c.SaveCheckpoint(ctx, name, value)
+}
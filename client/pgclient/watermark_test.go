@@ -0,0 +1,97 @@
+package pgclient_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotoolkit/client/pgclient"
+)
+
+type watermarkTest struct {
+	ID        int64     `db:"id" primarykey:"true" pgli:"true"`
+	Name      string    `db:"name"`
+	UpdatedAt time.Time `db:"modified" pgli:"true"`
+}
+
+func TestGetMostRecentText(t *testing.T) {
+	text, err := pgclient.DefaultGetMostRecentText(watermarkTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`max( "id" ) as "id",`,
+		`max( "modified" ) as "modified"`,
+		`."watermarktest"`,
+		"having count(*) > 0",
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+	if strings.Contains(text, `"name"`) {
+		t.Errorf("only pgli fields should be selected:\n%s", text)
+	}
+
+	if text, err := pgclient.DefaultGetMostRecentText(structTest); err == nil || !strings.Contains(err.Error(), "has no watermark") {
+		t.Errorf("expected a watermark error for a struct without pgli fields, got %v:\n%s", err, text)
+	}
+}
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	in := watermarkTest{ID: 42, Name: "ignored", UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)}
+	data, err := pgclient.MarshalWatermark(in, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{"id":42,"modified":"2024-01-02T03:04:05.000000006Z"}`; string(data) != expect {
+		t.Errorf("got %s, expected %s", data, expect)
+	}
+
+	out := watermarkTest{Name: "kept"}
+	if err := pgclient.UnmarshalWatermark(data, &out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || !out.UpdatedAt.Equal(in.UpdatedAt) || out.Name != "kept" {
+		t.Errorf("got %+v, expected the watermark of %+v", out, in)
+	}
+
+	if err := pgclient.UnmarshalWatermark([]byte(`{"id":"x"}`), &out, cfg); err == nil {
+		t.Error("expected an error for a mistyped watermark")
+	}
+	if err := pgclient.UnmarshalWatermark(data, out, cfg); err == nil {
+		t.Error("expected an error for a non-pointer destination")
+	}
+}
+
+func TestWatermarkRequiresLastInsertFields(t *testing.T) {
+	c := pgclient.NewClient()
+	if _, _, err := pgclient.MostRecent[StructTest](context.Background(), &c); err == nil || !strings.Contains(err.Error(), "has no watermark") {
+		t.Errorf("expected a watermark error, got %v", err)
+	}
+	if err := c.SaveCheckpoint(context.Background(), "", structTest); err == nil || !strings.Contains(err.Error(), "has no watermark") {
+		t.Errorf("expected a watermark error, got %v", err)
+	}
+}
+
+func TestCheckpointTable(t *testing.T) {
+	cfg := pgclient.TemplateConfig
+	cfg.Schema = "meta"
+	cfg.Table = cfg.CheckpointTable
+	text, err := pgclient.TemplateToText(pgclient.Checkpoint{}, pgclient.PGTemplates.CreateTable, &cfg, pgclient.FuncMap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`CREATE TABLE IF NOT EXISTS "meta"."load_checkpoints"`,
+		`"watermark"	jsonb`,
+		`"updated_at"	timestamp with time zone NOT NULL DEFAULT now(),`,
+		`PRIMARY KEY ( "name" )`,
+	} {
+		if !strings.Contains(text, expect) {
+			t.Errorf("expected %q in:\n%s", expect, text)
+		}
+	}
+}
@@ -1,16 +1,45 @@
 package client
 
-import "html/template"
+import (
+	"html/template"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
 
 type TemplatorConfig struct {
-	Schema           string // explicitly assign schema, most systems default to schema used in connection
-	Table            string // explicitly assign table, will attempt to get from TableNameTags or struct type
-	TableNameTags    []string
-	FieldNameTags    []string
-	LastInsertTags   []string // for checking the 'last insert' values in destination
-	TaggedFieldsOnly bool
-	DataTypeTag      string
-	PrimaryKeyTag    string
+	Schema              string // explicitly assign schema, most systems default to schema used in connection
+	Table               string // explicitly assign table, will attempt to get from TableNameTags or struct type
+	TableNameTags       []string
+	FieldNameTags       []string
+	LastInsertTags      []string // for checking the 'last insert' values in destination
+	TaggedFieldsOnly    bool
+	DataTypeTag         string
+	PrimaryKeyTag       string
+	PreviousNameTag     string              // a column's previous name, so schema diffs can rename instead of drop/add
+	ChildTag            string              // marks fields holding child structs, which get their own table instead of a column
+	ParentPrimaryKeyTag string              // marks a child's column holding its parent's primary key
+	IndexTag            string              // secondary indexes, values are an index method and/or a group name for multi-column indexes
+	UniqueTag           string              // unique indexes, values are an optional group name for multi-column uniqueness
+	IndexWhereTag       string              // predicate for partial indexes on the field's index and unique groups
+	NotNullTag          string              // forces NOT NULL, or with a false value (eg "-") prevents inferring it
+	DefaultTag          string              // column DEFAULT expression
+	CheckTag            string              // column CHECK expression
+	GeneratedTag        string              // GENERATED ALWAYS AS expression, these columns are never written
+	CompositeTag        string              // names a composite type for struct fields, which are otherwise jsonb
+	ReturningTag        string              // fields read back after inserts, along with DefaultTag and GeneratedTag fields
+	IdentityTag         string              // GENERATED BY DEFAULT AS IDENTITY columns, or GENERATED ALWAYS with an "always" value
+	CreatedAtTag        string              // timestamps defaulting to now() on insert, never updated
+	UpdatedAtTag        string              // timestamps defaulting to now() on insert, set to now() on update
+	VersionTag          string              // optimistic locking version, starts at 1 and updates must match it
+	RowHashTag          string              // history tables, hash of the non-key fields used to detect changes
+	ValidFromTag        string              // history tables, when a version became current, part of the primary key
+	ValidToTag          string              // history tables, when a version was replaced, NULL while current
+	BatchIDTag          string              // set to the batch id by Load, to trace rows back to their LoadBatch
+	BatchSchema         string              // schema of BatchTable and CheckpointTable, defaults to Schema
+	BatchTable          string              // when set, Load records a LoadBatch row here for every call, eg "load_batches"
+	CheckpointTable     string              // watermarks of LastInsertTags fields saved for incremental loads
+	NullableByDefault   bool                // don't infer NOT NULL for non-pointer, non-Nullable fields
+	UpdateStrategy      meta.UpdateStrategy // default write strategy, unset behaves as meta.AppendChanges
 }
 
 func (tc *TemplatorConfig) Merge(cfg ...TemplatorConfig) *TemplatorConfig {
@@ -38,6 +67,81 @@ func (tc *TemplatorConfig) Merge(cfg ...TemplatorConfig) *TemplatorConfig {
 		if cf.PrimaryKeyTag != "" {
 			tc.PrimaryKeyTag = cf.PrimaryKeyTag
 		}
+		if cf.PreviousNameTag != "" {
+			tc.PreviousNameTag = cf.PreviousNameTag
+		}
+		if cf.ChildTag != "" {
+			tc.ChildTag = cf.ChildTag
+		}
+		if cf.ParentPrimaryKeyTag != "" {
+			tc.ParentPrimaryKeyTag = cf.ParentPrimaryKeyTag
+		}
+		if cf.IndexTag != "" {
+			tc.IndexTag = cf.IndexTag
+		}
+		if cf.UniqueTag != "" {
+			tc.UniqueTag = cf.UniqueTag
+		}
+		if cf.IndexWhereTag != "" {
+			tc.IndexWhereTag = cf.IndexWhereTag
+		}
+		if cf.NotNullTag != "" {
+			tc.NotNullTag = cf.NotNullTag
+		}
+		if cf.DefaultTag != "" {
+			tc.DefaultTag = cf.DefaultTag
+		}
+		if cf.CheckTag != "" {
+			tc.CheckTag = cf.CheckTag
+		}
+		if cf.GeneratedTag != "" {
+			tc.GeneratedTag = cf.GeneratedTag
+		}
+		if cf.CompositeTag != "" {
+			tc.CompositeTag = cf.CompositeTag
+		}
+		if cf.ReturningTag != "" {
+			tc.ReturningTag = cf.ReturningTag
+		}
+		if cf.IdentityTag != "" {
+			tc.IdentityTag = cf.IdentityTag
+		}
+		if cf.CreatedAtTag != "" {
+			tc.CreatedAtTag = cf.CreatedAtTag
+		}
+		if cf.UpdatedAtTag != "" {
+			tc.UpdatedAtTag = cf.UpdatedAtTag
+		}
+		if cf.VersionTag != "" {
+			tc.VersionTag = cf.VersionTag
+		}
+		if cf.RowHashTag != "" {
+			tc.RowHashTag = cf.RowHashTag
+		}
+		if cf.ValidFromTag != "" {
+			tc.ValidFromTag = cf.ValidFromTag
+		}
+		if cf.ValidToTag != "" {
+			tc.ValidToTag = cf.ValidToTag
+		}
+		if cf.BatchIDTag != "" {
+			tc.BatchIDTag = cf.BatchIDTag
+		}
+		if cf.BatchSchema != "" {
+			tc.BatchSchema = cf.BatchSchema
+		}
+		if cf.BatchTable != "" {
+			tc.BatchTable = cf.BatchTable
+		}
+		if cf.CheckpointTable != "" {
+			tc.CheckpointTable = cf.CheckpointTable
+		}
+		if cf.NullableByDefault {
+			tc.NullableByDefault = true
+		}
+		if cf.UpdateStrategy != 0 {
+			tc.UpdateStrategy = cf.UpdateStrategy
+		}
 	}
 	return tc
 }
@@ -48,15 +152,62 @@ type Templator struct {
 	CreateSchema    string
 	DropSchema      string
 	CreateTable     string
+	CreateIndexes   string // run after CreateTable, see TemplatorConfig.IndexTag
 	CreateTempTable string
 	DropTable       string
+	DropTempTable   string
 	Get             string
-	GetMostRecent   string
+	GetPage         string // keyset paginated Get, ordered by primary key
+	GetMostRecent   string // max() of the LastInsertTags fields, no row when the table is empty
+	GetVersion      string // current VersionTag value of a row, by primary key
 	Put             string
 	PutTempToTable  string
 	FuncMap         template.FuncMap
 	Data            map[string]any // any additional 'data' passed to templates
 
+	// Put and PutTempToTable implement meta.AppendChanges, these implement the other strategies.
+	// meta.ReplaceAll and meta.History need the whole batch in a temp table, so they're PutTempToTable only.
+	PutAppendAll                 string
+	PutReplaceChanges            string
+	PutTempToTableAppendAll      string
+	PutTempToTableReplaceChanges string
+	PutTempToTableReplaceAll     string
+	PutTempToTableHistory        string
+
+	// VersionConflicts lists the temp table rows whose VersionTag value doesn't match the table's, including
+	// zero (NULL) versions of existing keys, see Load
+	VersionConflicts string
+	// HistoryConflicts lists the changed temp table rows whose current version isn't older than .validfrom
+	HistoryConflicts string
+}
+
+// PutStrategy returns the Put template for the given strategy, Put delegates meta.ReplaceAll and meta.History to Load.
+// Structs without a primary key have nothing to conflict on, so every strategy inserts all of their rows.
+func (t Templator) PutStrategy(strategy meta.UpdateStrategy) string {
+	switch strategy {
+	case meta.AppendAll:
+		return t.PutAppendAll
+	case meta.ReplaceChanges:
+		return t.PutReplaceChanges
+	default:
+		return t.Put
+	}
+}
+
+// PutTempToTableStrategy returns the PutTempToTable template for the given strategy
+func (t Templator) PutTempToTableStrategy(strategy meta.UpdateStrategy) string {
+	switch strategy {
+	case meta.AppendAll:
+		return t.PutTempToTableAppendAll
+	case meta.ReplaceChanges:
+		return t.PutTempToTableReplaceChanges
+	case meta.ReplaceAll:
+		return t.PutTempToTableReplaceAll
+	case meta.History:
+		return t.PutTempToTableHistory
+	default:
+		return t.PutTempToTable
+	}
 }
 
 func NewTemplatorConfig(cfg ...TemplatorConfig) TemplatorConfig {
@@ -0,0 +1,46 @@
+package meta_test
+
+import (
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+const benchTemplate = `insert into {{ .Struct.TagIdentifier "table" | tolower }} (
+	{{- $names := .Struct.Fields.TagNames "db" | tolowerslices -}}
+	{{ $names | join ", " }}
+) values ( :{{ $names | join ", :" }} )`
+
+func BenchmarkExecuteTemplate(b *testing.B) {
+	str, err := meta.ToStruct(structExample)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := str.ExecuteTemplate(benchTemplate, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFields(b *testing.B) {
+	str, err := meta.ToStruct(structExample)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fields := str.Fields()
+		_ = fields.WithTagTrue("struct").TagNames("db")
+	}
+}
+
+func BenchmarkToValueMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if vm := meta.ToValueMap(structExample, "vm"); len(vm) == 0 {
+			b.Fatal("empty value map")
+		}
+	}
+}
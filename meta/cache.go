@@ -0,0 +1,112 @@
+package meta
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+// Struct metadata depends only on a reflect.Type or tag string, so it is parsed once and cached.
+// Cached values are shared and must never be modified, public methods return copies.
+var (
+	visibleFieldsCache sync.Map // reflect.Type -> []reflect.StructField
+	tagsCache          sync.Map // tag string -> Tags
+	templateCache      sync.Map // templateKey -> *template.Template
+	templateCount      atomic.Int64
+)
+
+// MaxCachedTemplates caps the number of parsed templates cached. Once it's reached other templates are
+// parsed on every execution, so callers that build template text per call can't grow the cache without bound.
+var MaxCachedTemplates int64 = 1024
+
+// visibleFields returns the exported, non-anonymous fields of a struct type, per reflect.VisibleFields
+func visibleFields(t reflect.Type) []reflect.StructField {
+	if fields, ok := visibleFieldsCache.Load(t); ok {
+		return fields.([]reflect.StructField)
+	}
+	var fields []reflect.StructField
+	for _, field := range reflect.VisibleFields(t) {
+		if field.IsExported() && !field.Anonymous {
+			fields = append(fields, field)
+		}
+	}
+	visibleFieldsCache.Store(t, fields)
+	return fields
+}
+
+// cachedTags returns the shared, parsed Tags for a tag string
+func cachedTags(s string) Tags {
+	if tags, ok := tagsCache.Load(s); ok {
+		return tags.(Tags)
+	}
+	tags := parseTags(s)
+	tagsCache.Store(s, tags)
+	return tags
+}
+
+// Clone returns a deep copy of t
+func (t Tags) Clone() Tags {
+	if t == nil {
+		return nil
+	}
+	clone := make(Tags, len(t))
+	for k, v := range t {
+		clone[k] = append(Tag(nil), v...)
+	}
+	return clone
+}
+
+// templateKey identifies a parsed template. Functions are bound when a template is parsed, so
+// templates are cached by the names of the functions available and rebound on every execution.
+type templateKey struct {
+	text    string
+	funcs   string
+	options string
+}
+
+// parsedTemplate returns a clone of the cached template for tpl, with funcs bound
+func parsedTemplate(tpl string, funcs template.FuncMap) (*template.Template, error) {
+	names := []string{}
+	for name := range TemplateFuncMap {
+		names = append(names, name)
+	}
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := templateKey{
+		text:    tpl,
+		funcs:   strings.Join(names, ","),
+		options: strings.Join(TemplateOptions, ","),
+	}
+
+	cached, ok := templateCache.Load(key)
+	if !ok {
+		parsed, err := template.
+			New("").
+			Option(TemplateOptions...).
+			Funcs(TemplateFuncMap).
+			Funcs(funcs).
+			Parse(tpl)
+		if err != nil {
+			return nil, err
+		}
+		if templateCount.Add(1) > MaxCachedTemplates {
+			templateCount.Add(-1)
+			return parsed, nil
+		}
+		var loaded bool
+		if cached, loaded = templateCache.LoadOrStore(key, parsed); loaded {
+			templateCount.Add(-1)
+		}
+	}
+
+	clone, err := cached.(*template.Template).Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.Funcs(TemplateFuncMap).Funcs(funcs), nil
+}
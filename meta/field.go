@@ -87,30 +87,35 @@ func (f Field) Tags() Tags {
 	return ToTags(string(f.StructField.Tag))
 }
 
+// tags returns the shared cached tags, for read-only use
+func (f Field) tags() Tags {
+	return cachedTags(string(f.StructField.Tag))
+}
+
 // HasTag returns true any of the given tag keys exist
 func (f Field) HasTag(keys ...any) bool {
-	return f.Tags().Exists(keys...)
+	return f.tags().Exists(keys...)
 }
 
 // HasTagValue returns true if it has both the key and value
 func (f Field) HasTagValue(key, value string) bool {
-	return f.Tags().Contains(key, value)
+	return f.tags().Contains(key, value)
 }
 
 // HasTagTrue returns true if its tags satisfy Tags.True
 func (f Field) HasTagTrue(keys ...any) bool {
-	return f.Tags().True(keys...)
+	return f.tags().True(keys...)
 }
 
 // HasTagFalse returns true if any Tag satisfies Tags.False
 func (f Field) HasTagFalse(keys ...any) bool {
-	return f.Tags().False(keys...)
+	return f.tags().False(keys...)
 }
 
 // TagName ranges through the provided keys in order and returns the first non-blank, non-false value, or field.Name if none are found.
 func (f Field) TagName(keys ...any) string {
 	for _, key := range keys {
-		tag := f.Tags().Value(key)
+		tag := f.tags().Value(key)
 		if len(tag) > 0 && tag.True() && tag[0] != "" {
 			return tag[0]
 		}
@@ -132,14 +137,17 @@ func (f Field) TagIdentifier(keys ...any) string {
 
 // Tag returns the tag for the given key, according to Tags.Tag
 func (f Field) Tag(key string) Tag {
-	return f.Tags().Tag(key)
+	if tag := f.tags().Tag(key); tag != nil {
+		return append(Tag{}, tag...)
+	}
+	return nil
 }
 
 // TagValueAtIndex returns the first tag.True, non-blank value for keys in the given order.
 // If no match is found, it returns the empty string.
 func (f Field) TagValueAtIndex(index int, keys ...any) string {
 	for _, key := range keys {
-		tag := f.Tags().Value(key)
+		tag := f.tags().Value(key)
 		if len(tag) > 0 && tag.True() && tag[0] != "" {
 			return tag[0]
 		}
@@ -148,7 +156,7 @@ func (f Field) TagValueAtIndex(index int, keys ...any) string {
 }
 
 func (f Field) NonEmptyTagValue(keys ...any) string {
-	if tag := f.Tags().NonEmptyValue(keys...); len(tag) > 0 {
+	if tag := f.tags().NonEmptyValue(keys...); len(tag) > 0 {
 		return tag[0]
 	}
 	return ""
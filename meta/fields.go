@@ -76,6 +76,17 @@ func (f Fields) WithTagFalse(key string) Fields {
 	return fields
 }
 
+// WithoutTagTrue returns a subset of Fields whose Tags don't satisfy Tags.True
+func (f Fields) WithoutTagTrue(keys ...any) Fields {
+	fields := Fields{}
+	for _, field := range f {
+		if !field.HasTagTrue(keys...) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
 // WithoutTag returns a subset of Fields that do not have any of the given keys
 func (f Fields) WithoutTag(keys ...any) Fields {
 	fields := Fields{}
@@ -47,3 +47,26 @@ func TestToFields(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldTagsNotShared(t *testing.T) {
+	type tagged struct {
+		ID string `db:"id,primary" table:"tagged"`
+	}
+	fields, err := meta.ToFields(tagged{})
+	if err != nil || len(fields) != 1 {
+		t.Fatalf("ToFields: %v, %d fields", err, len(fields))
+	}
+
+	// tags are cached, so callers modifying their copy must not affect others
+	tags := fields[0].Tags()
+	tags["db"][0] = "changed"
+	tags.Append("db", "other")
+	fields[0].Tag("db")[1] = "changed"
+
+	if got := fields[0].TagName("db"); got != "id" {
+		t.Errorf("TagName: got %q, expected %q", got, "id")
+	}
+	if got := fields[0].Tags()["db"]; !reflect.DeepEqual(got, meta.Tag{"id", "primary"}) {
+		t.Errorf("Tags: got %v, expected %v", got, meta.Tag{"id", "primary"})
+	}
+}
@@ -2,6 +2,7 @@ package meta
 
 import "database/sql"
 
+// SQLResult is a sql.Result with the values of a row returned by the statement, eg by RETURNING
 type SQLResult struct {
 	sql.Result
 	Values map[string]any
@@ -38,3 +39,21 @@ func (r SQLResults) RowsAffected() (int64, error) {
 func (r SQLResults) AddResult(results ...sql.Result) SQLResults {
 	return append(r, results...)
 }
+
+// Values returns the Values of each SQLResult, in order. Results without values are skipped.
+func (r SQLResults) Values() []map[string]any {
+	var values []map[string]any
+	for _, result := range r {
+		switch result := result.(type) {
+		case SQLResult:
+			if result.Values != nil {
+				values = append(values, result.Values)
+			}
+		case *SQLResult:
+			if result != nil && result.Values != nil {
+				values = append(values, result.Values)
+			}
+		}
+	}
+	return values
+}
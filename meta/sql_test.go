@@ -0,0 +1,29 @@
+package meta_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+type rowsResult int64
+
+func (r rowsResult) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestSQLResultsValues(t *testing.T) {
+	results := meta.SQLResults{
+		meta.SQLResult{Result: rowsResult(1), Values: map[string]any{"id": 1}},
+		rowsResult(0),
+		&meta.SQLResult{Result: rowsResult(1), Values: map[string]any{"id": 2}},
+		meta.SQLResult{Result: rowsResult(0)},
+	}
+	expect := []map[string]any{{"id": 1}, {"id": 2}}
+	if got := results.Values(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Values: got %v, expected %v", got, expect)
+	}
+	if n, err := results.RowsAffected(); err != nil || n != 2 {
+		t.Errorf("RowsAffected: got %d, %v", n, err)
+	}
+}
@@ -195,6 +195,7 @@ func (s Struct) LastNameSpace() string {
 // ExecuteTemplate parses a string and executes it with any additional funcs and data. All data, including the reciever
 // is passed to text/template as a map. By default, the reciever's map key is its type - eg {{ .Struct }} references a calling Struct.
 // By default, it passes missingkey=zero, you can override this by changing TemplateOptions
+// Parsed templates are cached by their text, function names, and TemplateOptions, up to MaxCachedTemplates.
 // See TemplateFuncMap for additional functions included by default.
 // See TemplateDataNames if you really need to change data map key names.
 func (s *Struct) ExecuteTemplate(tpl string, funcs template.FuncMap, data map[string]any) (string, error) {
@@ -206,12 +207,7 @@ func (s *Struct) ExecuteTemplate(tpl string, funcs template.FuncMap, data map[st
 		d[k] = v
 	}
 
-	parsedTpl, err := template.
-		New("").
-		Option(TemplateOptions...).
-		Funcs(TemplateFuncMap).
-		Funcs(funcs).
-		Parse(tpl)
+	parsedTpl, err := parsedTemplate(tpl, funcs)
 	if err != nil {
 		return "", err
 	}
@@ -225,22 +221,15 @@ func (s *Struct) ExecuteTemplate(tpl string, funcs template.FuncMap, data map[st
 }
 
 func (s *Struct) Fields() Fields {
-
-	sfs := reflect.VisibleFields(s.Value.Type())
-	sfmap := map[string]reflect.StructField{}
-	for _, sf := range sfs {
-		if sf.IsExported() && !sf.Anonymous {
-			sfmap[sf.Name] = sf
-		}
-	}
-
+	// Children are in visibleFields order
+	sfs := visibleFields(s.Value.Type())
 	var fields Fields
-	for _, child := range s.Value.Children() {
+	for i, child := range s.Value.Children() {
 		field := Field{
 			Name:        child.Name,
 			Parent:      s,
 			Value:       child,
-			StructField: sfmap[child.Name],
+			StructField: sfs[i],
 		}
 		fields = append(fields, field)
 	}
@@ -256,6 +245,8 @@ func (s *Struct) ExtractDataByName(names ...any) map[string]Data {
 	return data
 }
 
+// Extract returns a Struct for s and each of its named child fields, keyed by name. Each child's
+// Data is the concatenation of that field's values across s.Data, and its Parent is s.
 func (s *Struct) Extract(names ...any) map[string]Struct {
 
 	structs := map[string]Struct{}
@@ -271,10 +262,6 @@ func (s *Struct) Extract(names ...any) map[string]Struct {
 	for _, row := range s.Data {
 		rowValue := reflect.ValueOf(row)
 		nms := ToStringSlice(names...)
-		fmt.Println(names...)
-		fmt.Printf("%#v\n", names)
-		fmt.Println(nms)
-		fmt.Printf("%#v\n", nms)
 		for _, childName := range nms {
 			childRowData := ToData(rowValue.FieldByName(childName).Interface())
 			data[childName] = append(data[childName], childRowData...)
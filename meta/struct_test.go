@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/exiledavatar/gotoolkit/meta"
 )
@@ -72,3 +73,25 @@ joinslices:
 	fmt.Printf("%-.200s\n", strings.Repeat("-", 200))
 
 }
+
+func TestExecuteTemplateCacheLimit(t *testing.T) {
+	defer func(max int64) { meta.MaxCachedTemplates = max }(meta.MaxCachedTemplates)
+	meta.MaxCachedTemplates = 0
+
+	s, err := meta.ToStruct(structExample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		got, err := s.ExecuteTemplate(fmt.Sprintf(`{{ .n }}-%d-{{ upper "x" }}`, i), template.FuncMap{"upper": strings.ToUpper}, map[string]any{"n": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := fmt.Sprintf("%d-%d-X", i, i); got != expect {
+			t.Errorf("got %q, expected %q", got, expect)
+		}
+	}
+	if _, err := s.ExecuteTemplate(`{{ .n `, nil, nil); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -9,9 +9,15 @@ import (
 // map[tagLabel][]tagvalues
 type Tags map[string]Tag
 
+var tagPattern = regexp.MustCompile(`(?m)(?P<key>\w*):\"(?P<value>[^"]*)\"`)
+
+// ToTags parses a struct tag string
 func ToTags(s string) Tags {
-	pattern := regexp.MustCompile(`(?m)(?P<key>\w*):\"(?P<value>[^"]*)\"`)
-	matches := pattern.FindAllStringSubmatch(s, -1)
+	return cachedTags(s).Clone()
+}
+
+func parseTags(s string) Tags {
+	matches := tagPattern.FindAllStringSubmatch(s, -1)
 	var tkv = map[string]Tag{}
 	for _, match := range matches {
 		tkv[match[1]] = strings.Split(match[2], ",")
@@ -63,13 +69,9 @@ func (t Tags) ByKeys(keys ...any) Tags {
 // one in ConfigTagFalse (by default this is just "-")
 func (t Tags) False(keys ...any) bool {
 	ks := ToStringSlice(keys...)
-	var out bool
 	for _, key := range ks {
-		switch tag, ok := t[key]; {
-		case out:
-			return out
-		case ok && tag != nil:
-			out = tag.False()
+		if tag, ok := t[key]; ok && tag != nil && tag.False() {
+			return true
 		}
 	}
 	return false
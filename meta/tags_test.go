@@ -0,0 +1,33 @@
+package meta_test
+
+import (
+	"testing"
+
+	"github.com/exiledavatar/gotoolkit/meta"
+)
+
+func TestTagsFalse(t *testing.T) {
+	var testCases = []struct {
+		Name   string
+		Tags   string
+		Keys   []any
+		Expect bool
+	}{
+		{Name: "false", Tags: `vm:"-"`, Keys: []any{"vm"}, Expect: true},
+		{Name: "false with more values", Tags: `vm:"-,omitempty"`, Keys: []any{"vm"}, Expect: true},
+		{Name: "true", Tags: `vm:"name"`, Keys: []any{"vm"}},
+		{Name: "empty", Tags: `vm:""`, Keys: []any{"vm"}},
+		{Name: "missing", Tags: `db:"-"`, Keys: []any{"vm"}},
+		{Name: "no keys", Tags: `vm:"-"`},
+		{Name: "first of several keys", Tags: `vm:"-" db:"name"`, Keys: []any{"vm", "db"}, Expect: true},
+		{Name: "last of several keys", Tags: `vm:"name" db:"-"`, Keys: []any{"vm", "db"}, Expect: true},
+		{Name: "key slice", Tags: `db:"-"`, Keys: []any{[]string{"vm", "db"}}, Expect: true},
+	}
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			if got := meta.ToTags(v.Tags).False(v.Keys...); got != v.Expect {
+				t.Errorf("%s.False(%v): got %v, expected %v", v.Tags, v.Keys, got, v.Expect)
+			}
+		})
+	}
+}
@@ -37,10 +37,26 @@ func (m TypeMap) ToType(value any) string {
 	if err != nil {
 		panic(err)
 	}
+	if nt, ok := NullableType(t.Type()); ok {
+		return m.To[nt]
+	}
 	return m.To[t.Type()]
 
 }
 
+// NullableType returns T for types shaped like null.Nullable[T]: a struct with only a V field
+// and a bool Valid field. The null package is a separate module, so it's matched by shape.
+func NullableType(t reflect.Type) (reflect.Type, bool) {
+	if t == nil || t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return nil, false
+	}
+	v, valid := t.Field(0), t.Field(1)
+	if v.Name != "V" || valid.Name != "Valid" || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+	return v.Type, true
+}
+
 // FromType takes the external type and returns the reflect.Type,
 // if it exists in the From typemap
 func (m TypeMap) FromType(externalType string) reflect.Type {
@@ -60,7 +76,11 @@ func (m TypeMaps) From(system, externalType string) reflect.Type {
 // To takes any go value and returns the exernal type in the given system,
 // if it exists in the typemap
 func (m TypeMaps) To(system string, value any) string {
-	return m[system].To[reflect.TypeOf(value)]
+	t := reflect.TypeOf(value)
+	if nt, ok := NullableType(t); ok {
+		t = nt
+	}
+	return m[system].To[t]
 }
 
 var TypeMappings = TypeMaps{
@@ -86,8 +106,8 @@ var Postgres = TypeMap{
 		reflect.TypeOf(float32(1.0)):     "float4",
 		reflect.TypeOf(float64(1.0)):     "float8",
 		reflect.TypeOf(time.Time{}):      "timestamp with time zone",
-		reflect.TypeOf([]byte{}):         "bytea[]",
-		nil:                              "bytea[]", // serves as a default
+		reflect.TypeOf([]byte{}):         "bytea",
+		nil:                              "text", // serves as a default
 	},
 }
 
@@ -100,7 +120,7 @@ var Postgres = TypeMap{
 // // GoToPGTypeMap represents the default type mapping
 // // we expect to use when sending data to postgres
 // var GoToPGTypeMap = map[string]string{
-// 	"default":          "bytea[]",
+// 	"default":          "text",
 // 	"string":           "text",
 // 	"Date":             "date",
 // 	"qgenda.Date":      "date",
@@ -1,15 +1,30 @@
 package meta
 
+// UpdateStrategy describes how a write treats rows that already exist in the destination.
+// The zero value is unset, writers treat it as AppendChanges.
 type UpdateStrategy int8
 
 func (u UpdateStrategy) String() string {
-	
-	return ""
+	switch u {
+	case AppendChanges:
+		return "AppendChanges"
+	case AppendAll:
+		return "AppendAll"
+	case ReplaceChanges:
+		return "ReplaceChanges"
+	case ReplaceAll:
+		return "ReplaceAll"
+	case History:
+		return "History"
+	default:
+		return ""
+	}
 }
 
 const (
-	AppendChanges UpdateStrategy = iota
-	AppendAll
-	ReplaceChanges
-	ReplaceAll
+	AppendChanges  UpdateStrategy = iota + 1 // insert new keys only, existing rows are left as is
+	AppendAll                                // insert every row, regardless of keys
+	ReplaceChanges                           // insert new keys, update existing rows whose non-key values differ
+	ReplaceAll                               // upsert every row, then delete keys missing from the batch
+	History                                  // close the current version of changed rows and insert the new one, keeping history
 )
@@ -88,12 +88,9 @@ func (v *Value) Children() []Value {
 			children = append(children, child)
 		}
 	case kind == reflect.Struct:
-		for _, field := range reflect.VisibleFields(v.Type()) {
-			if field.Anonymous || !field.IsExported() {
-				continue
-			}
-			fieldValue := v.Value.FieldByName(field.Name)
-			if fieldValue.Kind() == reflect.Invalid {
+		for _, field := range visibleFields(v.Type()) {
+			fieldValue, err := v.Value.FieldByIndexErr(field.Index)
+			if err != nil || fieldValue.Kind() == reflect.Invalid {
 				fieldValue = reflect.New(field.Type).Elem()
 			}
 			// fmt.Println(field.Name, ":\t", fieldValue.Type())
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/exiledavatar/gotoolkit/meta"
@@ -51,3 +52,42 @@ func TestToValueMap(t *testing.T) {
 // func TestToValue(t *testing.T) {
 
 // }
+
+func TestToValueMapTagFilters(t *testing.T) {
+	type excluded struct {
+		A string `vm:"-"`
+		B string
+		C string `vm:"c"`
+	}
+	type included struct {
+		A string `vm:"a"`
+		B string
+	}
+	type untagged struct {
+		A string
+		B string
+	}
+
+	var testCases = []struct {
+		Name   string
+		Input  any
+		Expect []string
+	}{
+		{Name: "false tags are excluded", Input: excluded{"a", "b", "c"}, Expect: []string{"B", "C"}},
+		{Name: "only true tags are included", Input: included{"a", "b"}, Expect: []string{"A"}},
+		{Name: "untagged fields are all included", Input: untagged{"a", "b"}, Expect: []string{"A", "B"}},
+	}
+	for _, v := range testCases {
+		t.Run(v.Name, func(t *testing.T) {
+			vm := meta.ToValueMap(v.Input, "vm")
+			keys := make([]string, 0, len(vm))
+			for k := range vm {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			if !reflect.DeepEqual(keys, v.Expect) {
+				t.Errorf("got %v, expected %v", keys, v.Expect)
+			}
+		})
+	}
+}
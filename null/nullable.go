@@ -1,8 +1,10 @@
 package null
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"reflect"
 )
 
 type Nullable[T any] struct {
@@ -58,17 +60,58 @@ func (n *Nullable[T]) UnmarshalJSON(b []byte) error {
 	}
 }
 
-// TODO: is there a general purpose scan function like json.Unmarshal?
-// func (n *Nullable[T]) Scan(src any) error {
-// 	if src == nil {
-// 		n.V = *new(T)
-// 		n.Valid = false
-// 		return nil
-// 	}
-// 	*n = New()
+// Scan implements sql.Scanner. A nil src is invalid (null), otherwise src is assigned to V
+// directly, through T's own Scan method, or by converting between compatible kinds.
+func (n *Nullable[T]) Scan(src any) error {
+	if src == nil {
+		*n = Nullable[T]{}
+		return nil
+	}
 
-// 	return nil
-// }
+	var v T
+	if scanner, ok := interface{}(&v).(sql.Scanner); ok {
+		if err := scanner.Scan(src); err != nil {
+			return err
+		}
+		*n = New(v)
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	tv := reflect.ValueOf(&v).Elem()
+	switch {
+	case sv.Type().AssignableTo(tv.Type()):
+		tv.Set(sv)
+	case convertible(sv.Type(), tv.Type()):
+		tv.Set(sv.Convert(tv.Type()))
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, n)
+	}
+	*n = New(v)
+	return nil
+}
+
+// convertible limits reflect's conversions to ones that preserve meaning - eg int64 to string
+// is legal in go but produces a rune, not digits
+func convertible(from, to reflect.Type) bool {
+	if !from.ConvertibleTo(to) {
+		return false
+	}
+	numeric := func(k reflect.Kind) bool {
+		return (k >= reflect.Int && k <= reflect.Float64)
+	}
+	textual := func(t reflect.Type) bool {
+		return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+	}
+	switch {
+	case numeric(from.Kind()) && numeric(to.Kind()):
+		return true
+	case textual(from) && textual(to):
+		return true
+	default:
+		return from.Kind() == to.Kind()
+	}
+}
 
 // TODO: write Value
 // func (n Nullable[T]) Value() (driver.Value, error) {
@@ -0,0 +1,65 @@
+package null_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/exiledavatar/gotools/null"
+)
+
+// scan scans src into a new Nullable[T], returning it as any so cases of different types fit in one table
+func scan[T any](src any) (any, error) {
+	var n null.Nullable[T]
+	err := n.Scan(src)
+	return n, err
+}
+
+func TestNullableScan(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		scan    func(any) (any, error)
+		src     any
+		expect  any
+		wantErr bool
+	}{
+		{"nil", scan[int64], nil, null.Nullable[int64]{}, false},
+		{"int64", scan[int64], int64(42), null.New(int64(42)), false},
+		{"int64 to int32", scan[int32], int64(42), null.New(int32(42)), false},
+		{"float64", scan[float64], 1.5, null.New(1.5), false},
+		{"int64 to float64", scan[float64], int64(2), null.New(2.0), false},
+		{"bool", scan[bool], true, null.New(true), false},
+		{"bytes", scan[[]byte], []byte("raw"), null.New([]byte("raw")), false},
+		{"bytes to string", scan[string], []byte("text"), null.New("text"), false},
+		{"string", scan[string], "text", null.New("text"), false},
+		{"string to bytes", scan[[]byte], "raw", null.New([]byte("raw")), false},
+		{"time", scan[time.Time], now, null.New(now), false},
+		{"scanner", scan[sql.NullString], "text", null.New(sql.NullString{String: "text", Valid: true}), false},
+		{"int64 to string", scan[string], int64(65), null.Nullable[string]{}, true},
+		{"string to int64", scan[int64], "42", null.Nullable[int64]{}, true},
+		{"bool to int64", scan[int64], true, null.Nullable[int64]{}, true},
+		{"string to time", scan[time.Time], "2024-01-02", null.Nullable[time.Time]{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.scan(tc.src)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, expected error %t", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got %#v, expected %#v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestNullableScanResetsToNull(t *testing.T) {
+	n := null.New("stale")
+	if err := n.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n.Valid || n.V != "" {
+		t.Errorf("got %#v, expected null", n)
+	}
+}
@@ -29,6 +29,9 @@ func (m Map) ToType(value any) string {
 	if err != nil {
 		panic(err)
 	}
+	if nt, ok := meta.NullableType(t.Type()); ok {
+		return m.To[nt]
+	}
 	return m.To[t.Type()]
 }
 
@@ -51,7 +54,11 @@ func (m Maps) From(system, externalType string) reflect.Type {
 // To takes any go value and returns the exernal type in the given system,
 // if it exists in the typemap
 func (m Maps) To(system string, value any) string {
-	return m[system].To[reflect.TypeOf(value)]
+	t := reflect.TypeOf(value)
+	if nt, ok := meta.NullableType(t); ok {
+		t = nt
+	}
+	return m[system].To[t]
 }
 
 var TypeMaps = Maps{
@@ -77,8 +84,8 @@ var Postgres = Map{
 		reflect.TypeOf(float32(1.0)):     "float4",
 		reflect.TypeOf(float64(1.0)):     "float8",
 		reflect.TypeOf(time.Time{}):      "timestamp with time zone",
-		reflect.TypeOf([]byte{}):         "bytea[]",
-		nil:                              "bytea[]", // serves as a default
+		reflect.TypeOf([]byte{}):         "bytea",
+		nil:                              "text", // serves as a default
 	},
 }
 
@@ -91,7 +98,7 @@ var PGToGoTypeMap = map[string]string{
 // GoToPGTypeMap represents the default type mapping
 // we expect to use when sending data to postgres
 var GoToPGTypeMap = map[string]string{
-	"default":          "bytea[]",
+	"default":          "text",
 	"string":           "text",
 	"Date":             "date",
 	"qgenda.Date":      "date",